@@ -0,0 +1,117 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package clipboard provides a pluggable interface to the clipboard so Clip
+// can work on desktops, over SSH, inside tmux, and in headless environments.
+package clipboard
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Names of the available clipboard backends
+const (
+	BackendAuto   = "auto"
+	BackendSystem = "system"
+	BackendOSC52  = "osc52"
+	BackendTmux   = "tmux"
+	BackendFile   = "file"
+	BackendMemory = "memory"
+)
+
+var (
+	// ErrUnavailable is returned when a backend can't be used in the current environment
+	ErrUnavailable = errors.New("clipboard unavailable")
+
+	// ErrReadUnsupported is returned by backends that can only write to the clipboard
+	ErrReadUnsupported = errors.New("reading from this clipboard backend is not supported")
+
+	// ErrUnknownBackend is returned by New for backend names it doesn't know
+	ErrUnknownBackend = errors.New("unknown clipboard backend")
+)
+
+// Clipboard is the interface implemented by every clipboard backend
+type Clipboard interface {
+	// ReadAll returns the current contents of the clipboard
+	ReadAll() (string, error)
+
+	// WriteAll replaces the contents of the clipboard with text
+	WriteAll(text string) error
+}
+
+// Options holds backend specific settings
+type Options struct {
+	// File is the path used by the `file` backend
+	File string
+}
+
+// Backends returns the names of all selectable backends
+func Backends() []string {
+	return []string{BackendAuto, BackendSystem, BackendOSC52, BackendTmux, BackendFile, BackendMemory}
+}
+
+// New returns the clipboard backend with the given name. An empty name or
+// `auto` will pick a backend based on the environment (see Detect).
+func New(backend string, opts Options) (Clipboard, error) {
+	backend = strings.ToLower(strings.TrimSpace(backend))
+	if backend == "" || backend == BackendAuto {
+		backend = Detect()
+	}
+
+	switch backend {
+	case BackendSystem:
+		return NewSystem()
+	case BackendOSC52:
+		return NewOSC52(), nil
+	case BackendTmux:
+		return NewTmux()
+	case BackendFile:
+		if opts.File == "" {
+			return nil, fmt.Errorf("%w: no file configured for the '%s' backend", ErrUnavailable, BackendFile)
+		}
+		return NewFile(opts.File), nil
+	case BackendMemory:
+		return NewMemory(), nil
+	default:
+		return nil, fmt.Errorf("%w '%s' (valid backends: %s)", ErrUnknownBackend, backend, strings.Join(Backends(), ", "))
+	}
+}
+
+// Detect picks a clipboard backend based on the environment:
+//
+//	$SSH_TTY set                     -> osc52 (reaches the clipboard of the local terminal)
+//	$WAYLAND_DISPLAY or $DISPLAY set -> system
+//	$TMUX set                        -> tmux buffers
+//	otherwise                        -> system
+func Detect() string {
+	switch {
+	case os.Getenv("SSH_TTY") != "":
+		return BackendOSC52
+	case os.Getenv("WAYLAND_DISPLAY") != "" || os.Getenv("DISPLAY") != "":
+		return BackendSystem
+	case os.Getenv("TMUX") != "":
+		return BackendTmux
+	default:
+		return BackendSystem
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package clipboard
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// File stores the clipboard in a plain file, which is handy for headless
+// machines and CI
+type File struct {
+	Path string
+}
+
+// NewFile returns a clipboard backed by the file at path
+func NewFile(path string) *File {
+	return &File{Path: path}
+}
+
+func (f *File) ReadAll() (string, error) {
+	buf, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read clipboard file: %w", err)
+	}
+
+	return string(buf), nil
+}
+
+func (f *File) WriteAll(text string) error {
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for clipboard file: %w", err)
+	}
+
+	if err := os.WriteFile(f.Path, []byte(text), 0600); err != nil {
+		return fmt.Errorf("failed to write clipboard file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package clipboard
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory is an in-memory clipboard. It only lives as long as the process,
+// so it's mostly useful as a fake in tests.
+type Memory struct {
+	mu          sync.Mutex
+	text        string
+	subscribers []chan string
+}
+
+// NewMemory returns an empty in-memory clipboard
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) ReadAll() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.text, nil
+}
+
+func (m *Memory) WriteAll(text string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.text = text
+	for _, ch := range m.subscribers {
+		// don't block writers on slow watchers
+		select {
+		case ch <- text:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Changes implements Notifier
+func (m *Memory) Changes(ctx context.Context) <-chan string {
+	ch := make(chan string, 16)
+
+	m.mu.Lock()
+	m.subscribers = append(m.subscribers, ch)
+	m.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for i, sub := range m.subscribers {
+			if sub == ch {
+				m.subscribers = append(m.subscribers[:i], m.subscribers[i+1:]...)
+				break
+			}
+		}
+	}()
+
+	return ch
+}
@@ -0,0 +1,75 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package clipboard
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// OSC52 writes to the clipboard of the terminal emulator using the OSC 52
+// escape sequence. It works over SSH and inside containers as long as the
+// terminal supports it, but the clipboard can't be read back.
+type OSC52 struct {
+	// Out is where the escape sequence is written. If nil, /dev/tty is
+	// used, falling back to stderr.
+	Out io.Writer
+
+	// Tmux wraps the sequence in a tmux passthrough so it reaches the
+	// outer terminal
+	Tmux bool
+}
+
+// NewOSC52 returns an OSC 52 clipboard writing to the controlling terminal
+func NewOSC52() *OSC52 {
+	return &OSC52{Tmux: os.Getenv("TMUX") != ""}
+}
+
+func (o *OSC52) ReadAll() (string, error) {
+	return "", fmt.Errorf("%w: %s", ErrReadUnsupported, BackendOSC52)
+}
+
+func (o *OSC52) WriteAll(text string) error {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if o.Tmux {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+
+	out := o.Out
+	if out == nil {
+		tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+		if err != nil {
+			out = os.Stderr
+		} else {
+			defer tty.Close()
+			out = tty
+		}
+	}
+
+	if _, err := io.WriteString(out, seq); err != nil {
+		return fmt.Errorf("failed to write OSC 52 sequence to terminal: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package clipboard
+
+import (
+	"fmt"
+
+	"github.com/atotto/clipboard"
+)
+
+// System uses the native clipboard of the OS (pbcopy, xclip/xsel,
+// wl-clipboard, or the Windows clipboard API)
+type System struct{}
+
+// NewSystem returns the native OS clipboard, or ErrUnavailable if no
+// supported clipboard utility could be found
+func NewSystem() (*System, error) {
+	if clipboard.Unsupported {
+		return nil, fmt.Errorf("%w: no clipboard utility found (install xclip, xsel or wl-clipboard)", ErrUnavailable)
+	}
+
+	return &System{}, nil
+}
+
+func (s *System) ReadAll() (string, error) {
+	return clipboard.ReadAll()
+}
+
+func (s *System) WriteAll(text string) error {
+	return clipboard.WriteAll(text)
+}
@@ -0,0 +1,72 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package clipboard
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Tmux stores the clipboard in the tmux paste buffer
+type Tmux struct {
+	path string
+}
+
+// NewTmux returns a tmux buffer clipboard, or ErrUnavailable if tmux isn't
+// in the PATH
+func NewTmux() (*Tmux, error) {
+	path, err := exec.LookPath("tmux")
+	if err != nil {
+		return nil, fmt.Errorf("%w: tmux not found in PATH", ErrUnavailable)
+	}
+
+	return &Tmux{path: path}, nil
+}
+
+func (t *Tmux) ReadAll() (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(t.path, "save-buffer", "-")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// an empty buffer list isn't an error from our point of view
+		if strings.Contains(stderr.String(), "no buffers") {
+			return "", nil
+		}
+		return "", fmt.Errorf("tmux save-buffer failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return string(out), nil
+}
+
+func (t *Tmux) WriteAll(text string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command(t.path, "load-buffer", "-")
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("tmux load-buffer failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package clipboard
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultInterval is how often a Watcher polls backends that can't notify
+// it of changes
+const DefaultInterval = 500 * time.Millisecond
+
+// Notifier is implemented by backends that can report clipboard changes
+// themselves, so a Watcher doesn't need to poll them
+type Notifier interface {
+	// Changes returns a channel that receives the new clipboard contents
+	// after every write, until ctx is done
+	Changes(ctx context.Context) <-chan string
+}
+
+// Watcher reports changes to the contents of a clipboard
+type Watcher struct {
+	Clipboard Clipboard
+
+	// Interval between polls of the clipboard (DefaultInterval if unset)
+	Interval time.Duration
+
+	// OnChange is called with the new clipboard contents every time they change
+	OnChange func(text string)
+
+	// OnError is called when reading the clipboard fails. Errors are
+	// otherwise ignored so a temporarily unavailable clipboard doesn't
+	// stop the watcher.
+	OnError func(err error)
+}
+
+// Run watches the clipboard until ctx is done. The contents of the
+// clipboard when Run is called are not reported as a change.
+func (w *Watcher) Run(ctx context.Context) error {
+	last, err := w.Clipboard.ReadAll()
+	if errors.Is(err, ErrReadUnsupported) {
+		return err
+	}
+	if err != nil {
+		w.error(err)
+	}
+
+	if n, ok := w.Clipboard.(Notifier); ok {
+		changes := n.Changes(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case text := <-changes:
+				if text != last {
+					last = text
+					w.OnChange(text)
+				}
+			}
+		}
+	}
+
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			text, err := w.Clipboard.ReadAll()
+			if err != nil {
+				w.error(err)
+				continue
+			}
+
+			if text != last {
+				last = text
+				w.OnChange(text)
+			}
+		}
+	}
+}
+
+func (w *Watcher) error(err error) {
+	if w.OnError != nil {
+		w.OnError(err)
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package clipboard
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitTimeout bounds how long the tests wait for the watcher
+const waitTimeout = 5 * time.Second
+
+// fakeClipboard is a clipboard that can't notify watchers, so they have to
+// poll it
+type fakeClipboard struct {
+	mu   sync.Mutex
+	text string
+	err  error
+}
+
+func (f *fakeClipboard) ReadAll() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.text, f.err
+}
+
+func (f *fakeClipboard) WriteAll(text string) error {
+	f.set(text, nil)
+	return nil
+}
+
+func (f *fakeClipboard) set(text string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.text, f.err = text, err
+}
+
+// startWatcher runs a Watcher for cb until the test ends, and returns the
+// channels its changes and errors are sent to and the channel Run's result
+// is sent to
+func startWatcher(t *testing.T, ctx context.Context, cb Clipboard) (<-chan string, <-chan error, <-chan error) {
+	t.Helper()
+
+	changes := make(chan string, 16)
+	errs := make(chan error, 16)
+	done := make(chan error, 1)
+
+	w := &Watcher{
+		Clipboard: cb,
+		Interval:  time.Millisecond,
+		OnChange:  func(text string) { changes <- text },
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	}
+	go func() { done <- w.Run(ctx) }()
+
+	return changes, errs, done
+}
+
+// waitForSubscriber waits until a watcher is subscribed to the changes of m,
+// so writes after it aren't missed
+func waitForSubscriber(t *testing.T, m *Memory) {
+	t.Helper()
+
+	deadline := time.Now().Add(waitTimeout)
+	for time.Now().Before(deadline) {
+		m.mu.Lock()
+		n := len(m.subscribers)
+		m.mu.Unlock()
+		if n > 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("watcher never subscribed to the clipboard")
+}
+
+func expectChange(t *testing.T, changes <-chan string, want string) {
+	t.Helper()
+
+	select {
+	case got := <-changes:
+		if got != want {
+			t.Fatalf("change = %q, want %q", got, want)
+		}
+	case <-time.After(waitTimeout):
+		t.Fatalf("timed out waiting for change %q", want)
+	}
+}
+
+func expectNoChange(t *testing.T, changes <-chan string) {
+	t.Helper()
+
+	select {
+	case got := <-changes:
+		t.Fatalf("unexpected change %q", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func expectDone(t *testing.T, done <-chan error) error {
+	t.Helper()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(waitTimeout):
+		t.Fatal("timed out waiting for the watcher to stop")
+		return nil
+	}
+}
+
+func TestWatcherNotifier(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := NewMemory()
+	if err := m.WriteAll("initial"); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, _, done := startWatcher(t, ctx, m)
+	waitForSubscriber(t, m)
+
+	// the contents when the watcher starts aren't a change
+	for _, text := range []string{"initial", "a", "a", "b", "b", "a"} {
+		if err := m.WriteAll(text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectChange(t, changes, "a")
+	expectChange(t, changes, "b")
+	expectChange(t, changes, "a")
+	expectNoChange(t, changes)
+
+	cancel()
+	if err := expectDone(t, done); err != nil {
+		t.Fatalf("Run() = %v, want nil", err)
+	}
+}
+
+func TestWatcherPolling(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cb := &fakeClipboard{text: "initial"}
+	changes, errs, done := startWatcher(t, ctx, cb)
+	expectNoChange(t, changes)
+
+	cb.set("a", nil)
+	expectChange(t, changes, "a")
+	expectNoChange(t, changes)
+
+	// read errors are reported, and don't stop the watcher
+	readErr := errors.New("clipboard unavailable")
+	cb.set("", readErr)
+	select {
+	case err := <-errs:
+		if !errors.Is(err, readErr) {
+			t.Fatalf("OnError(%v), want %v", err, readErr)
+		}
+	case <-time.After(waitTimeout):
+		t.Fatal("timed out waiting for the read error")
+	}
+
+	cb.set("b", nil)
+	expectChange(t, changes, "b")
+
+	cancel()
+	if err := expectDone(t, done); err != nil {
+		t.Fatalf("Run() = %v, want nil", err)
+	}
+}
+
+func TestWatcherCancelled(t *testing.T) {
+	for name, cb := range map[string]Clipboard{
+		"notifier": NewMemory(),
+		"polling":  &fakeClipboard{},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			changes, _, done := startWatcher(t, ctx, cb)
+			if err := expectDone(t, done); err != nil {
+				t.Fatalf("Run() = %v, want nil", err)
+			}
+			expectNoChange(t, changes)
+		})
+	}
+}
+
+func TestWatcherReadUnsupported(t *testing.T) {
+	cb := &fakeClipboard{err: ErrReadUnsupported}
+	_, _, done := startWatcher(t, context.Background(), cb)
+
+	if err := expectDone(t, done); !errors.Is(err, ErrReadUnsupported) {
+		t.Fatalf("Run() = %v, want %v", err, ErrReadUnsupported)
+	}
+}
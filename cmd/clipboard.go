@@ -0,0 +1,74 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/viper"
+
+	"github.com/tjhop/clip/clipboard"
+)
+
+// getClipboard returns the clipboard backend selected by the `--backend`
+// flag/`clipboard.backend` config key
+func getClipboard() (clipboard.Clipboard, error) {
+	return clipboard.New(viper.GetString("clipboard.backend"), clipboard.Options{
+		File: viper.GetString("clipboard.file"),
+	})
+}
+
+// writeToClipboard writes text to the configured clipboard and records it in
+// the clipboard history under the given source
+func writeToClipboard(source, text string) error {
+	if err := writeToClipboardOnly(text); err != nil {
+		return err
+	}
+
+	recordHistory(source, text)
+	return nil
+}
+
+// writeToClipboardOnly writes text to the configured clipboard without
+// recording it in the clipboard history
+func writeToClipboardOnly(text string) error {
+	cb, err := getClipboard()
+	if err != nil {
+		return fmt.Errorf("failed to open clipboard: %w", err)
+	}
+
+	if err := cb.WriteAll(text); err != nil {
+		return clipboardError(err)
+	}
+
+	return nil
+}
+
+// clipboardError marks errors from reading/writing the clipboard as the
+// clipboard being unavailable, unless the backend already classified them
+func clipboardError(err error) error {
+	if errors.Is(err, clipboard.ErrUnavailable) || errors.Is(err, clipboard.ErrReadUnsupported) {
+		return err
+	}
+
+	return fmt.Errorf("%w: %w", clipboard.ErrUnavailable, err)
+}
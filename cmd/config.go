@@ -0,0 +1,550 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tjhop/clip/clipboard"
+	"github.com/tjhop/clip/helpers"
+)
+
+// errConfigKeyNotSet is returned for config keys that don't have a value
+var errConfigKeyNotSet = errors.New("config key not set")
+
+var configSetString bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and edit the Clip config file",
+	Long: `Read and edit the Clip config file. Keys are dotted paths into the config,
+ie 'history.max_entries' or 'vars.team'.
+
+Changes are made in place, so comments and the order of keys in the config
+file are kept.
+
+Example:
+  clip config get editor
+  clip config set vars.team sre
+  clip config set history.max_entries 100
+  clip config unset vars.team
+  clip config list
+  clip config validate`,
+	// edit, path and validate are how a broken config file gets fixed, so
+	// they run even if it can't be loaded
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		err := initClip(cmd)
+		if errors.Is(err, helpers.ErrInvalidConfig) && (cmd == configEditCmd || cmd == configPathCmd || cmd == configValidateCmd) {
+			return nil
+		}
+		return err
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the value of a config key",
+	Long: `Print the value of a config key, after the active profile, environment and
+command line flags are applied. Maps and lists are printed as YAML.`,
+	Args: usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key := args[0]
+		if !viper.IsSet(key) {
+			return fmt.Errorf("%w: '%s'", errConfigKeyNotSet, key)
+		}
+
+		value := viper.Get(key)
+		return printOutput(value, func() error {
+			switch value.(type) {
+			case map[string]interface{}, []interface{}:
+				out, err := yaml.Marshal(value)
+				if err != nil {
+					return fmt.Errorf("failed to marshal '%s': %w", key, err)
+				}
+				fmt.Print(string(out))
+			default:
+				fmt.Println(value)
+			}
+			return nil
+		})
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a config key in the config file",
+	Long: `Set a config key in the config file. The value is parsed as YAML, so 'true',
+'100' and '[a, b]' set a bool, an int and a list. Use --string to always set a
+string.`,
+	Args: usageArgs(cobra.ExactArgs(2)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, raw := args[0], args[1]
+
+		value, err := parseConfigValue(raw, configSetString)
+		if err != nil {
+			return &usageError{err: err}
+		}
+
+		var decoded interface{}
+		if err := value.Decode(&decoded); err != nil {
+			return &usageError{err: fmt.Errorf("invalid value for '%s': %w", key, err)}
+		}
+
+		// check the value before writing it, so a typo doesn't leave the
+		// config file unusable
+		if check, known := configKeyCheck(key); !known {
+			fmt.Fprintf(os.Stderr, "Warning: '%s' is not a known config key\n", key)
+		} else if err := check(decoded); err != nil {
+			return &usageError{err: fmt.Errorf("invalid value for '%s': %w", key, err)}
+		}
+
+		err = editConfigFile(viper.ConfigFileUsed(), func(doc *helpers.Document) error {
+			return doc.Set(key, value)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set '%s': %w", key, err)
+		}
+		return printOutput(configKeyOutput{Key: key, Value: decoded}, func() error { return nil })
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:     "unset <key>",
+	Aliases: []string{"delete"},
+	Short:   "Remove a config key from the config file",
+	Args:    usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		found := false
+		err := editConfigFile(viper.ConfigFileUsed(), func(doc *helpers.Document) error {
+			found = doc.Delete(args[0])
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to unset '%s': %w", args[0], err)
+		}
+		if !found {
+			return fmt.Errorf("%w in config file: '%s'", errConfigKeyNotSet, args[0])
+		}
+		return printOutput(configKeyOutput{Key: args[0]}, func() error { return nil })
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List all config keys and their values",
+	Args:    usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		settings := make(map[string]interface{})
+		flattenConfig("", viper.AllSettings(), settings)
+
+		keys := make([]string, 0, len(settings))
+		for k := range settings {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		return printOutput(settings, func() error {
+			for _, k := range keys {
+				value := settings[k]
+				if _, ok := value.(string); !ok {
+					if buf, err := json.Marshal(value); err == nil {
+						value = string(buf)
+					}
+				}
+				fmt.Printf("%s=%v\n", k, value)
+			}
+			return nil
+		})
+	},
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open the config file in your editor",
+	Args:  usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := openInEditor(viper.ConfigFileUsed()); err != nil {
+			return fmt.Errorf("failed to open config file: %w", err)
+		}
+
+		// catch mistakes while they're fresh
+		return validateConfigFile(viper.ConfigFileUsed())
+	},
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the location of the config file",
+	Args:  usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return printOutput(configPathOutput{Path: viper.ConfigFileUsed()}, func() error {
+			fmt.Println(viper.ConfigFileUsed())
+			return nil
+		})
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file for invalid values and unknown keys",
+	Args:  usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateConfigFile(viper.ConfigFileUsed()); err != nil {
+			return err
+		}
+		valid := true
+		return printOutput(configPathOutput{Path: viper.ConfigFileUsed(), Valid: &valid}, func() error {
+			fmt.Printf("Config file %s is valid\n", viper.ConfigFileUsed())
+			return nil
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configGetCmd, configSetCmd, configUnsetCmd, configListCmd, configEditCmd, configPathCmd, configValidateCmd)
+
+	// command Line flags
+	configSetCmd.Flags().BoolVar(&configSetString, "string", false, "set the value as a string instead of parsing it as YAML")
+}
+
+// baseConfigFileString is the config file written on the first run of clip.
+// Everything else is left at its default, see `clip config list`.
+const baseConfigFileString string = `# Clip configuration. See README.md for all of the settings, and run
+# 'clip config list' to see the values in effect.
+
+# editor used by 'clip edit' and 'clip config edit'
+editor: nano
+
+# directory Clip templates are created in
+templatedir: ""
+
+# variables available to all templates
+vars:
+  name: Clip User
+`
+
+// writeBaseConfigFile writes the base config file for a new install
+func writeBaseConfigFile(filename, templateDir string) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	doc, err := helpers.ParseDocument([]byte(baseConfigFileString))
+	if err != nil {
+		return fmt.Errorf("failed to parse base config file: %w", err)
+	}
+	if err := doc.Set("templatedir", templateDir); err != nil {
+		return err
+	}
+
+	return doc.WriteFile(filename)
+}
+
+// editConfigFile applies edit to a config file in place, keeping its
+// comments and the order of its keys
+func editConfigFile(filename string, edit func(doc *helpers.Document) error) error {
+	doc, err := helpers.LoadDocument(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := edit(doc); err != nil {
+		return err
+	}
+
+	return doc.WriteFile(filename)
+}
+
+// parseConfigValue parses a value from the command line as YAML, falling
+// back to a plain string if it isn't valid YAML
+func parseConfigValue(raw string, asString bool) (*yaml.Node, error) {
+	var doc yaml.Node
+	if !asString && yaml.Unmarshal([]byte(raw), &doc) == nil && len(doc.Content) == 1 {
+		node := doc.Content[0]
+		node.Line, node.Column = 0, 0
+		return node, nil
+	}
+
+	node := &yaml.Node{}
+	if err := node.Encode(raw); err != nil {
+		return nil, fmt.Errorf("invalid value '%s': %w", raw, err)
+	}
+
+	return node, nil
+}
+
+// flattenConfig flattens nested settings into dotted keys
+func flattenConfig(prefix string, settings map[string]interface{}, flat map[string]interface{}) {
+	for k, v := range settings {
+		if m, ok := v.(map[string]interface{}); ok && len(m) > 0 {
+			flattenConfig(prefix+k+".", m, flat)
+			continue
+		}
+		flat[prefix+k] = v
+	}
+}
+
+// configCheck validates the value of a config key
+type configCheck func(value interface{}) error
+
+// configKeys are the known config keys. Keep this in sync with the
+// Configuration section of the README.
+var configKeys = map[string]configCheck{
+	"editor":              checkString,
+	"templatedir":         checkString,
+	"templatedirs":        checkStringList,
+	"system_templatedirs": checkBool,
+	"fuzzy":               checkBool,
+	"vars":                checkMap,
+	"tagvars":             checkTagVars,
+	"profile":             checkString,
+	"profiles":            checkMap,
+	"clipboard.backend":   checkBackend,
+	"clipboard.file":      checkString,
+	"history.enabled":     checkBool,
+	"history.file":        checkString,
+	"history.max_entries": checkNonNegativeInt,
+	"history.max_age":     checkAge,
+	"usage.enabled":       checkBool,
+	"usage.file":          checkString,
+	"watch.interval":      checkDuration,
+	"watch.hooks":         checkStringList,
+}
+
+// configKeyCheck returns the check for a key, which can be inside `vars`,
+// `tagvars` or a profile
+func configKeyCheck(key string) (configCheck, bool) {
+	key = strings.ToLower(key)
+	if rest, ok := strings.CutPrefix(key, "profiles."); ok {
+		if _, setting, ok := strings.Cut(rest, "."); ok {
+			key = setting
+		} else {
+			return checkMap, true
+		}
+	}
+
+	if strings.HasPrefix(key, "vars.") {
+		return func(interface{}) error { return nil }, true
+	}
+	if rest, ok := strings.CutPrefix(key, "tagvars."); ok {
+		if strings.Contains(rest, ".") {
+			return func(interface{}) error { return nil }, true
+		}
+		return checkMap, true
+	}
+
+	check, ok := configKeys[key]
+	return check, ok
+}
+
+// validateConfigFile checks every key in a config file. Invalid values are
+// errors, while unknown keys (which are probably typos) are only warnings.
+func validateConfigFile(filename string) error {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("%w: failed to read config file '%s': %w", helpers.ErrInvalidConfig, filename, err)
+	}
+
+	var cfg map[string]interface{}
+	if err := yaml.Unmarshal(buf, &cfg); err != nil {
+		return fmt.Errorf("%w: failed to parse config file '%s': %w", helpers.ErrInvalidConfig, filename, err)
+	}
+
+	problems, warnings := validateSettings("", cfg)
+
+	if name, ok := cfg["profile"].(string); ok && name != "" {
+		profiles, _ := cfg["profiles"].(map[string]interface{})
+		if _, ok := profiles[name]; !ok {
+			problems = append(problems, fmt.Sprintf("profile: unknown profile '%s'", name))
+		}
+	}
+
+	if profiles, ok := cfg["profiles"].(map[string]interface{}); ok {
+		for name, settings := range profiles {
+			m, ok := settings.(map[string]interface{})
+			if !ok {
+				if settings != nil {
+					problems = append(problems, fmt.Sprintf("profiles.%s: must be a mapping of settings", name))
+				}
+				continue
+			}
+
+			delete(m, "profile")
+			p, w := validateSettings("profiles."+name+".", m)
+			problems, warnings = append(problems, p...), append(warnings, w...)
+		}
+	}
+
+	sort.Strings(warnings)
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("%w: %s:\n  %s", helpers.ErrInvalidConfig, filename, strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+// validateSettings checks the settings of a config file (or profile)
+// against the known config keys
+func validateSettings(prefix string, settings map[string]interface{}) (problems, warnings []string) {
+	for k, v := range settings {
+		key := strings.ToLower(k)
+		if check, ok := configKeys[key]; ok {
+			if err := check(v); err != nil {
+				problems = append(problems, fmt.Sprintf("%s%s: %v", prefix, k, err))
+			}
+			continue
+		}
+
+		// sections like `history` hold other keys
+		section, ok := v.(map[string]interface{})
+		if !ok || !isConfigSection(key) {
+			warnings = append(warnings, fmt.Sprintf("%s%s: unknown config key", prefix, k))
+			continue
+		}
+
+		for sk, sv := range section {
+			full := key + "." + strings.ToLower(sk)
+			check, ok := configKeys[full]
+			if !ok {
+				warnings = append(warnings, fmt.Sprintf("%s%s.%s: unknown config key", prefix, k, sk))
+				continue
+			}
+			if err := check(sv); err != nil {
+				problems = append(problems, fmt.Sprintf("%s%s.%s: %v", prefix, k, sk, err))
+			}
+		}
+	}
+
+	return problems, warnings
+}
+
+func isConfigSection(key string) bool {
+	for k := range configKeys {
+		if strings.HasPrefix(k, key+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func checkString(value interface{}) error {
+	if _, ok := value.(string); !ok && value != nil {
+		return fmt.Errorf("must be a string, got '%v'", value)
+	}
+	return nil
+}
+
+func checkBool(value interface{}) error {
+	if _, ok := value.(bool); !ok {
+		return fmt.Errorf("must be true or false, got '%v'", value)
+	}
+	return nil
+}
+
+func checkMap(value interface{}) error {
+	if _, ok := value.(map[string]interface{}); !ok && value != nil {
+		return fmt.Errorf("must be a mapping, got '%v'", value)
+	}
+	return nil
+}
+
+func checkTagVars(value interface{}) error {
+	if err := checkMap(value); err != nil {
+		return err
+	}
+	tagvars, _ := value.(map[string]interface{})
+	for tag, vars := range tagvars {
+		if err := checkMap(vars); err != nil {
+			return fmt.Errorf("vars for tag '%s' %w", tag, err)
+		}
+	}
+	return nil
+}
+
+func checkStringList(value interface{}) error {
+	list, ok := value.([]interface{})
+	if !ok && value != nil {
+		return fmt.Errorf("must be a list of strings, got '%v'", value)
+	}
+	for _, item := range list {
+		if _, ok := item.(string); !ok {
+			return fmt.Errorf("must be a list of strings, got item '%v'", item)
+		}
+	}
+	return nil
+}
+
+func checkNonNegativeInt(value interface{}) error {
+	if n, ok := value.(int); !ok || n < 0 {
+		return fmt.Errorf("must be a whole number of at least 0, got '%v'", value)
+	}
+	return nil
+}
+
+func checkAge(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("must be a duration like 72h or 30d, got '%v'", value)
+	}
+	_, err := parseAge(s)
+	return err
+}
+
+func checkDuration(value interface{}) error {
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("must be a duration like 500ms or 2s, got '%v'", value)
+	}
+	if _, err := time.ParseDuration(s); err != nil {
+		return fmt.Errorf("must be a duration like 500ms or 2s: %w", err)
+	}
+	return nil
+}
+
+func checkBackend(value interface{}) error {
+	if s, ok := value.(string); !ok || !slices.Contains(clipboard.Backends(), s) {
+		return fmt.Errorf("must be one of %s, got '%v'", strings.Join(clipboard.Backends(), ", "), value)
+	}
+	return nil
+}
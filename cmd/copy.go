@@ -24,12 +24,8 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
-	"strings"
 
-	"github.com/atotto/clipboard"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 
 	"github.com/tjhop/clip/helpers"
 )
@@ -38,43 +34,133 @@ var copyCmd = &cobra.Command{
 	Use:     "copy <Clip template>",
 	Aliases: []string{"load", "in"},
 	Short:   "Copy a Clip template/Stdin to your clipboard (default if just running `clip $arg`)",
-	Long:    `Copy a Clip template or command output from Stdin to your clipboard`,
-	Args:    cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	Long: `Copy a Clip template or command output from Stdin to your clipboard.
+
+Template variables can be set at copy time. From lowest to highest precedence:
+  vars in the Clip config file (then the project config file, if any)
+  tagvars in the Clip config file for the tags of the template
+  vars in the template
+  --values files (in the order given)
+  CLIP_VAR_<NAME> environment variables (<NAME> is lowercased)
+  --set-file key=path
+  --<var> value
+  --set key=value
+
+Variables declared in the template's schema or set in its vars can also be
+set with flags named after them, given after the template name (ie, 'clip
+copy standup --project foo'). Run 'clip copy <template> --help' to list them.
+Any other unknown flag is an error.
+
+If the template uses variables that aren't set by any of these, Clip
+prompts for them on the terminal. When not running interactively, missing
+variables are an error.`,
+	Args: usageArgs(cobra.MaximumNArgs(1)),
+	// template variables are also available as flags, which cobra doesn't
+	// know about until the template is loaded. They're checked by
+	// templateFlagVars once it is.
+	FParseErrWhitelist: cobra.FParseErrWhitelist{UnknownFlags: true},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rawArgs, err := commandArgs(cmd)
+		if err != nil {
+			return err
+		}
+		if err := checkFlagsBeforeTemplate(cmd, rawArgs); err != nil {
+			return err
+		}
+
 		if len(args) == 0 {
-			err := writeStdinToClipboard()
-			if err != nil {
-				fmt.Printf("Failed to copy from stdin: %v\n", err)
-			}
-		} else if len(args) == 1 {
-			templateFilename := filepath.Join(viper.GetString("templatedir"), os.Args[len(os.Args)-1]+".yml")
-			err := writeClipTemplateToClipboard(templateFilename)
-			if err != nil {
-				fmt.Printf("Failed to copy Clip template '%s' to clipboard: %v\n", strings.TrimSuffix(filepath.Base(templateFilename), filepath.Ext(templateFilename)), err)
+			if err := writeStdinToClipboard(); err != nil {
+				return fmt.Errorf("failed to copy from stdin: %w", err)
 			}
+			return nil
+		}
+
+		if err := writeClipTemplateToClipboard(cmd, args[0], rawArgs); err != nil {
+			return fmt.Errorf("failed to copy Clip template '%s' to clipboard: %w", args[0], err)
 		}
+
+		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(copyCmd)
+
+	// command Line flags
+	addVarFlags(copyCmd.Flags())
+}
+
+// templateHelpFunc wraps the default help of `copy` and the root command to
+// also describe the template given on the command line, if any
+func templateHelpFunc(defaultHelp func(*cobra.Command, []string)) func(*cobra.Command, []string) {
+	return func(cmd *cobra.Command, args []string) {
+		if (cmd == copyCmd || cmd == rootCmd) && cmd.Flags().NArg() == 1 {
+			name := cmd.Flags().Arg(0)
+
+			// help is shown before cobra initializes clip
+			err := initClip(cmd)
+			if err == nil {
+				var t helpers.Template
+				var tmpl helpers.TemplateFile
+				t, err = templateResolver().Find(name)
+				if err == nil {
+					tmpl, err = helpers.LoadTemplateFile(t.Path)
+				}
+				if err == nil {
+					printTemplateHelp(cmd.OutOrStdout(), cmd, name, tmpl)
+				}
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Couldn't load Clip template '%s': %v\n\n", name, err)
+			}
+		}
+
+		defaultHelp(cmd, args)
+	}
 }
 
-func writeClipTemplateToClipboard(filename string) error {
-	tmpl, err := helpers.LoadTemplateFile(filename)
+func writeClipTemplateToClipboard(cmd *cobra.Command, name string, rawArgs []string) error {
+	t, err := templateResolver().Find(name)
+	if err != nil {
+		return err
+	}
+
+	tmpl, err := helpers.LoadTemplateFile(t.Path)
+	if err != nil {
+		return fmt.Errorf("couldn't load Clip template file '%s': %w", t.Name, err)
+	}
+
+	// cobra ignores the unknown per-template flags, so parse them out of
+	// the raw command line now that we know which template is being used
+	templateVars, err := templateFlagVars(cmd, tmpl, rawArgs)
 	if err != nil {
-		return fmt.Errorf("couldn't load Clip template file '%s': %w", strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)), err)
+		return err
 	}
 
-	renderedTemplateString, err := helpers.ExecuteTemplate(tmpl)
+	overlays, err := varOverlays(templateVars)
 	if err != nil {
-		return fmt.Errorf("failed to render Go Template: %w", err)
+		return err
 	}
 
-	err = clipboard.WriteAll(renderedTemplateString)
+	renderedTemplateString, err := helpers.ExecuteTemplate(tmpl, helpers.RenderOptions{
+		Overlays: overlays,
+		Prompt:   promptForVars,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w", helpers.ErrRenderFailed, err)
+	}
+
+	// the values of secret variables end up in the rendered text, so
+	// templates that have any are kept out of the history
+	if tmpl.Template.Schema.HasSecrets() {
+		err = writeToClipboardOnly(renderedTemplateString)
+	} else {
+		err = writeToClipboard("template:"+t.Name, renderedTemplateString)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to write Clip template to clipboard: %w", err)
 	}
+	recordUsage(t.Name)
 
 	return nil
 }
@@ -85,7 +171,7 @@ func writeStdinToClipboard() error {
 		return fmt.Errorf("error reading from stdin: %w", err)
 	}
 
-	err = clipboard.WriteAll(string(input))
+	err = writeToClipboard("stdin", string(input))
 	if err != nil {
 		return fmt.Errorf("failed to write data from stdin to clipboard: %w", err)
 	}
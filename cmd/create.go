@@ -22,12 +22,16 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
+
+	"github.com/tjhop/clip/helpers"
 )
 
 const baseTemplateFileString string = `# See README.md for detailed information
@@ -50,39 +54,257 @@ template:
   text: |
 `
 
+// template content flags for `create`
+var (
+	createTags          []string // --tag
+	createVars          []string // --var key=value
+	createDescription   string   // --description
+	createText          string   // --text
+	createFromFile      string   // --from-file
+	createFromStdin     bool     // --from-stdin
+	createFromClipboard bool     // --from-clipboard
+	createFromTemplate  string   // --from-template
+	createLocal         bool     // --local
+)
+
 // createCmd represents the create command
 var createCmd = &cobra.Command{
 	Use:     "create <Clip template>",
 	Aliases: []string{"add", "make"},
 	Short:   "Create a new Clip template",
 	Long: `Create a Clip template. Clip templates are YAML files with embedded Go templates and variables.
+
+Templates can be organized into namespaces by using slashes in the name, which
+creates the matching subdirectories in the template directory.
+
+The tags, variables, description and text of the template can be given with
+flags, so templates can be created from scripts without running 'clip edit'.
+The text can come from only one of --text, --from-file, --from-stdin and
+--from-clipboard. With --from-template, the new template starts as a copy of
+an existing one, and the other flags are applied on top of it.
+
+New templates are created in your template directory. Inside a project with
+a .clip/ directory or .clip.yml file, --local creates the template in the
+project's .clip/templates directory instead.
+
+Example:
+  clip create standup
+  clip create work/oncall/handoff
+  clip create greeting --tag personal --var name=World --text 'Hello, {{ .name }}!'
+  git log -1 --format=%B | clip create commit-msg --from-stdin
+  clip create snippet --from-clipboard --description 'Captured from the clipboard'
+  clip create work/standup --from-template standup --tag work
+  clip create release-notes --local
 `,
-	Args: cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		templateFilename := filepath.Join(viper.GetString("templatedir"), os.Args[len(os.Args)-1]+".yml")
-		err := writeTemplateFile(templateFilename)
+	Args: usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		resolver := templateResolver()
+		if createLocal {
+			if projectTemplateDir == "" {
+				return &usageError{err: fmt.Errorf("--local: not in a project with Clip templates or config (no %s/ or %s.yml found)", projectDirName, projectDirName)}
+			}
+			resolver.NewRoot = projectTemplateDir
+		}
+
+		t, err := resolver.New(args[0])
+		if err == nil {
+			var doc *helpers.Document
+			doc, err = newTemplateDocument(cmd, resolver)
+			if err == nil {
+				err = writeTemplateDocument(t, doc)
+			}
+		}
 		if err != nil {
-			fmt.Printf("Call to create template failed: %v\n", err)
+			return fmt.Errorf("call to create template failed: %w", err)
+		}
+
+		// the new template takes precedence over any template with the same
+		// name in later template directories, and is shadowed by the ones
+		// in earlier directories (ie the project's)
+		if found, err := resolver.Lookup(t.Name); err == nil && len(found) > 1 {
+			i := slices.IndexFunc(found, func(other helpers.Template) bool { return other.Path == t.Path })
+			switch {
+			case i > 0:
+				fmt.Fprintf(os.Stderr, "Note: '%s' is shadowed by the template at %s\n", t.Name, found[0].Path)
+			case i == 0 && resolver.IsReadOnly(found[1]):
+				fmt.Fprintf(os.Stderr, "Note: '%s' shadows the read-only template at %s\n", t.Name, found[1].Path)
+			case i == 0:
+				fmt.Fprintf(os.Stderr, "Note: '%s' shadows the template at %s\n", t.Name, found[1].Path)
+			}
 		}
+		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(createCmd)
+
+	// command Line flags
+	createCmd.Flags().StringArrayVar(&createTags, "tag", []string{}, "add a tag to the template (can be repeated)")
+	createCmd.Flags().StringArrayVar(&createVars, "var", []string{}, "set a template variable (key=value, use dots for nested keys, can be repeated)")
+	createCmd.Flags().StringVar(&createDescription, "description", "", "description of the template")
+	createCmd.Flags().StringVar(&createText, "text", "", "text of the template")
+	createCmd.Flags().StringVar(&createFromFile, "from-file", "", "read the text of the template from a file")
+	createCmd.Flags().BoolVar(&createFromStdin, "from-stdin", false, "read the text of the template from stdin")
+	createCmd.Flags().BoolVar(&createFromClipboard, "from-clipboard", false, "use the current clipboard contents as the text of the template")
+	createCmd.Flags().StringVar(&createFromTemplate, "from-template", "", "start from a copy of an existing Clip template")
+	createCmd.Flags().BoolVar(&createLocal, "local", false, "create the template in the project's .clip/templates directory")
 }
 
-func writeTemplateFile(filename string) error {
-	// create template file if it doesn't exist
-	if _, err := os.Stat(filename); os.IsNotExist(err) {
-		err := os.WriteFile(filename, []byte(baseTemplateFileString), 0644)
+// newTemplateDocument builds a new template from the base template (or the
+// template given with --from-template) and the content flags of `create`
+func newTemplateDocument(cmd *cobra.Command, resolver *helpers.Resolver) (*helpers.Document, error) {
+	text, hasText, err := createTemplateText(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc *helpers.Document
+	if createFromTemplate != "" {
+		source, err := resolver.Find(createFromTemplate)
 		if err != nil {
-			return fmt.Errorf("failed to create template file: %w", err)
+			return nil, err
 		}
 
-		fmt.Printf("Clip template '%s' created\n", strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)))
+		doc, err = helpers.LoadDocument(source.Path)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't load Clip template file '%s': %w", source.Name, err)
+		}
 	} else {
-		fmt.Printf("A Clip template with the name '%s' already exists\n", strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)))
+		doc, err = helpers.ParseDocument([]byte(baseTemplateFileString))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse base template: %w", err)
+		}
+	}
+
+	if createDescription != "" {
+		if err := doc.SetBefore("description", "tags", createDescription); err != nil {
+			return nil, err
+		}
+	}
+
+	// skip tags the cloned template already has
+	var tags []interface{}
+	existing := make(map[string]bool)
+	if node := doc.Lookup("tags"); node != nil {
+		for _, item := range node.Content {
+			existing[item.Value] = true
+		}
+	}
+	for _, tag := range createTags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || existing[tag] {
+			continue
+		}
+		existing[tag] = true
+		tags = append(tags, tag)
+	}
+	if len(tags) > 0 {
+		if err := doc.Append("tags", tags...); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, kv := range createVars {
+		key, value, err := splitVarAssignment("--var", kv)
+		if err != nil {
+			return nil, err
+		}
+		if err := doc.Set("template.vars."+key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if hasText {
+		if err := doc.SetText("template.text", text); err != nil {
+			return nil, err
+		}
+	}
+
+	return doc, nil
+}
+
+// createTemplateText returns the text of a new template from whichever of
+// the text flags of `create` was given, if any
+func createTemplateText(cmd *cobra.Command) (string, bool, error) {
+	var sources []string
+	for flag, set := range map[string]bool{
+		"--text":           cmd.Flags().Changed("text"),
+		"--from-file":      createFromFile != "",
+		"--from-stdin":     createFromStdin,
+		"--from-clipboard": createFromClipboard,
+	} {
+		if set {
+			sources = append(sources, flag)
+		}
+	}
+	sort.Strings(sources)
+	if len(sources) > 1 {
+		return "", false, &usageError{err: fmt.Errorf("only one of --text, --from-file, --from-stdin and --from-clipboard can be given, got %s", strings.Join(sources, ", "))}
+	}
+
+	switch {
+	case cmd.Flags().Changed("text"):
+		return createText, true, nil
+	case createFromFile != "":
+		buf, err := os.ReadFile(createFromFile)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read template text: %w", err)
+		}
+		return string(buf), true, nil
+	case createFromStdin:
+		buf, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", false, fmt.Errorf("error reading from stdin: %w", err)
+		}
+		return string(buf), true, nil
+	case createFromClipboard:
+		cb, err := getClipboard()
+		if err != nil {
+			return "", false, fmt.Errorf("failed to open clipboard: %w", err)
+		}
+		str, err := cb.ReadAll()
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read clipboard contents: %w", clipboardError(err))
+		}
+		return str, true, nil
+	}
+
+	return "", false, nil
+}
+
+// writeTemplateFile writes the base template for a new template returned by
+// Resolver.New
+func writeTemplateFile(t helpers.Template) error {
+	doc, err := helpers.ParseDocument([]byte(baseTemplateFileString))
+	if err != nil {
+		return fmt.Errorf("failed to parse base template: %w", err)
+	}
+
+	return writeTemplateDocument(t, doc)
+}
+
+// writeTemplateDocument writes a new template, which is usually the base
+// template edited through the YAML node tree so its comments are kept
+func writeTemplateDocument(t helpers.Template, doc *helpers.Document) error {
+	// create template file if it doesn't exist
+	if _, err := os.Stat(t.Path); err == nil {
+		return fmt.Errorf("%w: '%s'", helpers.ErrAlreadyExists, t.Name)
+	}
+
+	// namespaced templates live in subdirectories
+	err := os.MkdirAll(filepath.Dir(t.Path), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create template directory: %w", err)
+	}
+
+	err = doc.WriteFile(t.Path)
+	if err != nil {
+		return fmt.Errorf("failed to create template file: %w", err)
 	}
 
-	return nil
+	return printOutput(templateChangeOutput{Name: t.Name, Path: t.Path}, func() error {
+		fmt.Printf("Clip template '%s' created\n", t.Name)
+		return nil
+	})
 }
@@ -26,11 +26,11 @@ import (
 	"log"
 	"os"
 	"os/exec"
-	"path/filepath"
-	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/tjhop/clip/helpers"
 )
 
 var (
@@ -48,13 +48,12 @@ Clip will check the following locations for the editor to use:
   $EDITOR environment variable
   Default (nano)
 `,
-	Args: cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		templateFilename := filepath.Join(viper.GetString("templatedir"), os.Args[len(os.Args)-1]+".yml")
-		err := openClipTemplateInEditor(templateFilename)
-		if err != nil {
-			fmt.Printf("Failed to open Clip template for editing: %v\n", err)
+	Args: usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := openClipTemplateInEditor(args[0]); err != nil {
+			return fmt.Errorf("failed to open Clip template for editing: %w", err)
 		}
+		return nil
 	},
 }
 
@@ -70,25 +69,41 @@ func init() {
 	}
 }
 
-func openClipTemplateInEditor(filename string) error {
-	var editor string
-
+func openClipTemplateInEditor(name string) error {
 	// check if clip template exists yet. if it doesn't, make it
-	if _, err := os.Stat(filename); os.IsNotExist(err) {
-		err = writeTemplateFile(filename)
+	resolver := templateResolver()
+	t, err := resolver.ResolveWritable(name)
+	if errors.Is(err, helpers.ErrNotFound) {
+		t, err = resolver.New(name)
+		if err == nil {
+			err = writeTemplateFile(t)
+		}
 		if err != nil {
 			return fmt.Errorf("call to create Clip template file failed: %w", err)
 		}
 	}
+	if err != nil {
+		return err
+	}
 
-	if viper.GetString("editor") != "" {
-		editor = viper.GetString("editor")
-		_, err := exec.LookPath(editor)
-		if err != nil {
-			return fmt.Errorf("could not find an editor named '%s' in your PATH: %w", editor, err)
-		}
-	} else {
-		return errors.New("no editor defined")
+	if err := openInEditor(t.Path); err != nil {
+		return fmt.Errorf("failed to open Clip template '%s': %w", t.Name, err)
+	}
+
+	return nil
+}
+
+// openInEditor opens filename in the configured editor and waits for it to
+// exit
+func openInEditor(filename string) error {
+	editor := viper.GetString("editor")
+	if editor == "" {
+		return fmt.Errorf("%w: no editor defined", helpers.ErrInvalidConfig)
+	}
+
+	_, err := exec.LookPath(editor)
+	if err != nil {
+		return fmt.Errorf("%w: could not find an editor named '%s' in your PATH: %w", helpers.ErrInvalidConfig, editor, err)
 	}
 
 	// build command to run
@@ -96,9 +111,9 @@ func openClipTemplateInEditor(filename string) error {
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	err := cmd.Run()
+	err = cmd.Run()
 	if err != nil {
-		return fmt.Errorf("failed to open Clip template '%s' in %s: %w", strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)), editor, err)
+		return fmt.Errorf("failed to run %s: %w", editor, err)
 	}
 
 	return nil
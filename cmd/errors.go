@@ -0,0 +1,92 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tjhop/clip/clipboard"
+	"github.com/tjhop/clip/helpers"
+	"github.com/tjhop/clip/history"
+)
+
+// Exit codes returned by clip. These are part of the CLI's interface (and
+// documented in the README), so don't renumber them.
+const (
+	exitOK                   = 0
+	exitError                = 1 // any error not covered below
+	exitUsage                = 2 // invalid arguments, flags, template names, or tag queries
+	exitNotFound             = 3 // template (or history entry, or config key) doesn't exist
+	exitAlreadyExists        = 4 // template already exists
+	exitRenderFailed         = 5 // template couldn't be parsed, rendered, or its vars are invalid
+	exitClipboardUnavailable = 6 // clipboard backend can't be used
+	exitInvalidConfig        = 7 // config file or settings are invalid
+)
+
+// usageError marks errors caused by invalid command line usage
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+// usageArgs wraps a cobra argument validator so its errors are reported
+// as usage errors
+func usageArgs(validate cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := validate(cmd, args); err != nil {
+			return &usageError{err: err}
+		}
+		return nil
+	}
+}
+
+// exitCode maps an error returned by a command to the exit code of clip
+func exitCode(err error) int {
+	var usageErr *usageError
+
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &usageErr), errors.Is(err, helpers.ErrInvalidName), errors.Is(err, helpers.ErrAmbiguous), errors.Is(err, helpers.ErrInvalidQuery):
+		return exitUsage
+	case errors.Is(err, helpers.ErrNotFound), errors.Is(err, history.ErrNoEntry), errors.Is(err, errConfigKeyNotSet):
+		return exitNotFound
+	case errors.Is(err, helpers.ErrAlreadyExists):
+		return exitAlreadyExists
+	case errors.Is(err, helpers.ErrRenderFailed):
+		return exitRenderFailed
+	case errors.Is(err, clipboard.ErrUnavailable), errors.Is(err, clipboard.ErrReadUnsupported):
+		return exitClipboardUnavailable
+	case errors.Is(err, helpers.ErrInvalidConfig), errors.Is(err, clipboard.ErrUnknownBackend):
+		return exitInvalidConfig
+	default:
+		return exitError
+	}
+}
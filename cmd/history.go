@@ -0,0 +1,275 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/tjhop/clip/history"
+)
+
+var (
+	historyLimit      int
+	historyRegex      bool
+	historyMaxEntries int
+	historyMaxAge     string
+)
+
+var historyCmd = &cobra.Command{
+	Use:     "history",
+	Aliases: []string{"hist"},
+	Short:   "Browse and reuse previous clipboard contents",
+	Long: `Browse and reuse everything Clip has written to the clipboard.
+
+Entries are numbered from newest to oldest, so entry 1 is the most recent one.
+
+Example:
+  clip history list
+  clip history show 3
+  clip history copy 3
+  clip history search "ticket"
+  clip history prune --max-entries 100
+  clip history clear`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return historyListCmd.RunE(cmd, args)
+	},
+}
+
+var historyListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List clipboard history entries",
+	Args:    usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries, err := getHistory().Load()
+		if err != nil {
+			return fmt.Errorf("call to list clipboard history failed: %w", err)
+		}
+
+		if historyLimit > 0 && len(entries) > historyLimit {
+			entries = entries[:historyLimit]
+		}
+
+		out := []historyOutput{}
+		for i, e := range entries {
+			out = append(out, newHistoryOutput(i+1, e))
+		}
+		return printOutput(out, func() error {
+			for i, e := range entries {
+				printHistoryEntry(i+1, e)
+			}
+			return nil
+		})
+	},
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show <N>",
+	Short: "Print a clipboard history entry",
+	Args:  usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entry, err := getHistoryEntry(args[0])
+		if err != nil {
+			return fmt.Errorf("call to show clipboard history entry failed: %w", err)
+		}
+
+		index, _ := strconv.Atoi(args[0])
+		return printOutput(newHistoryOutput(index, entry), func() error {
+			fmt.Println(entry.Content)
+			return nil
+		})
+	},
+}
+
+var historyCopyCmd = &cobra.Command{
+	Use:   "copy <N>",
+	Short: "Copy a clipboard history entry back to the clipboard",
+	Args:  usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entry, err := getHistoryEntry(args[0])
+		if err != nil {
+			return fmt.Errorf("call to copy clipboard history entry failed: %w", err)
+		}
+
+		err = writeToClipboard("history", entry.Content)
+		if err != nil {
+			return fmt.Errorf("call to copy clipboard history entry failed: %w", err)
+		}
+
+		return nil
+	},
+}
+
+var historySearchCmd = &cobra.Command{
+	Use:     "search <query>",
+	Aliases: []string{"grep", "find"},
+	Short:   "Search clipboard history entries",
+	Args:    usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		matches, err := getHistory().Search(args[0], historyRegex)
+		if err != nil {
+			return fmt.Errorf("call to search clipboard history failed: %w", err)
+		}
+
+		out := []historyOutput{}
+		for _, m := range matches {
+			out = append(out, newHistoryOutput(m.Index, m.Entry))
+		}
+		return printOutput(out, func() error {
+			for _, m := range matches {
+				printHistoryEntry(m.Index, m.Entry)
+			}
+			return nil
+		})
+	},
+}
+
+var historyClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Delete all clipboard history entries",
+	Args:  usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// the count is only for --output; a history file that can't be
+		// read is still cleared
+		store := getHistory()
+		entries, _ := store.Load()
+		if err := store.Clear(); err != nil {
+			return fmt.Errorf("call to clear clipboard history failed: %w", err)
+		}
+
+		return printOutput(historyRemovedOutput{Removed: len(entries)}, func() error {
+			fmt.Println("Clipboard history cleared")
+			return nil
+		})
+	},
+}
+
+var historyPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove clipboard history entries beyond the configured retention",
+	Args:  usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := getHistory()
+		if cmd.Flags().Changed("max-entries") {
+			store.MaxEntries = historyMaxEntries
+		}
+		if cmd.Flags().Changed("max-age") {
+			age, err := parseAge(historyMaxAge)
+			if err != nil {
+				return fmt.Errorf("call to prune clipboard history failed: %w", err)
+			}
+			store.MaxAge = age
+		}
+
+		removed, err := store.Prune()
+		if err != nil {
+			return fmt.Errorf("call to prune clipboard history failed: %w", err)
+		}
+
+		return printOutput(historyRemovedOutput{Removed: removed}, func() error {
+			fmt.Printf("Removed %d clipboard history entries\n", removed)
+			return nil
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyListCmd, historyShowCmd, historyCopyCmd, historySearchCmd, historyClearCmd, historyPruneCmd)
+
+	// command Line flags
+	historyListCmd.Flags().IntVarP(&historyLimit, "limit", "n", 0, "only list the N most recent entries")
+	historySearchCmd.Flags().BoolVar(&historyRegex, "regex", false, "treat the query as a regular expression")
+	historyPruneCmd.Flags().IntVar(&historyMaxEntries, "max-entries", 0, "number of entries to keep (overrides `history.max_entries`)")
+	historyPruneCmd.Flags().StringVar(&historyMaxAge, "max-age", "", "remove entries older than this, e.g. 72h or 30d (overrides `history.max_age`)")
+}
+
+// getHistory returns the history store configured by the `history.*` config keys
+func getHistory() *history.Store {
+	// an invalid max age is treated as "keep forever" rather than failing
+	// every clipboard write; `clip history prune --max-age` reports it
+	age, _ := parseAge(viper.GetString("history.max_age"))
+	return history.NewStore(viper.GetString("history.file"), viper.GetInt("history.max_entries"), age)
+}
+
+// recordHistory adds content to the clipboard history if it's enabled.
+// Failing to record history never fails the clipboard write itself.
+func recordHistory(source, content string) {
+	if !viper.GetBool("history.enabled") {
+		return
+	}
+
+	if _, err := getHistory().Add(source, content); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to record clipboard history: %v\n", err)
+	}
+}
+
+func getHistoryEntry(arg string) (history.Entry, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return history.Entry{}, &usageError{err: fmt.Errorf("'%s' is not a valid history entry number", arg)}
+	}
+
+	return getHistory().Get(n)
+}
+
+// parseAge parses a duration, additionally accepting a number of days (`30d`)
+func parseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid age '%s': %w", s, err)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	age, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age '%s': %w", s, err)
+	}
+
+	return age, nil
+}
+
+func newHistoryOutput(index int, e history.Entry) historyOutput {
+	return historyOutput{Index: index, Time: e.Time, Source: e.Source, Content: e.Content}
+}
+
+func printHistoryEntry(index int, e history.Entry) {
+	preview := []rune(strings.Join(strings.Fields(e.Content), " "))
+	if len(preview) > 60 {
+		preview = append(preview[:57], []rune("...")...)
+	}
+
+	fmt.Printf("%4d  %s  %-24s  %s\n", index, e.Time.Local().Format("2006-01-02 15:04:05"), e.Source, string(preview))
+}
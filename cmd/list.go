@@ -22,34 +22,79 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
+	"path"
+	"slices"
 	"sort"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 
 	"github.com/tjhop/clip/helpers"
+	"github.com/tjhop/clip/usage"
 )
 
 var (
-	tags     []string
-	tagsOnly bool
+	tagsOnly    bool
+	listTree    bool
+	listSources bool
+	listLong    bool
+	listSort    string
+	listLimit   int
+	listFilters []string
 )
 
+// orders for `list --sort`
+const (
+	sortName     = "name"
+	sortModified = "modified"
+	sortUsed     = "used"
+	sortFrecency = "frecency"
+)
+
+var listSorts = []string{sortName, sortModified, sortUsed, sortFrecency}
+
 // listCmd represents the list command
 var listCmd = &cobra.Command{
 	Use: "list",
 	Long: `List available Clip templates (can be filtered by tag) or list the available tags.
 
+--tags lists the templates with any of the given tags. --query takes a tag
+query, where terms are combined with AND, OR, NOT and parentheses (terms
+next to each other are ANDed). A term matches a template if one of its tags
+matches it: exactly, as a glob if it contains *, ? or [...], or as a regular
+expression if it's wrapped in slashes (/^go/). Quote tags with spaces or
+tags named like an operator ("on call").
+
+Tags can be hierarchical, like 'work/aws', and filtering by a parent tag
+('work') also matches its children. --filter selects templates by name
+instead, with globs like 'work/*'. A * doesn't match a '/', but a glob that
+matches a namespace matches every template in it, so 'work/*' and 'work'
+both match 'work/oncall/handoff'.
+
+--long lists the templates as a table with their tags, description, last
+modified and last used times, and size. --sort orders them by name,
+modification time (newest first), last use (most recent first), or
+frecency, which ranks templates by how often and how recently they've been
+copied.
+
 Example:
   clip list
   clip list --tags-only
-  clip list --tags personal,work`,
+  clip list --tags personal,work
+  clip list --query 'work AND (oncall OR release) AND NOT archived'
+  clip list --query 'lang-* OR /^go/'
+  clip list --tree
+  clip list --tags-only --tree
+  clip list --sources
+  clip list --long --sort frecency --limit 10
+  clip list -l --filter 'work/*' --sort modified`,
 	Short: "List available Clip templates/tags (default if just running `clip`)",
-	Run: func(cmd *cobra.Command, args []string) {
-		list()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return list()
 	},
 }
 
@@ -57,55 +102,111 @@ func init() {
 	rootCmd.AddCommand(listCmd)
 
 	// command Line flags
-	listCmd.Flags().StringSliceVar(&tags, "tags", []string{}, "comma separated list of tags to filter templates")
+	addSelectionFlags(listCmd.Flags())
 	listCmd.Flags().BoolVar(&tagsOnly, "tags-only", false, "list all tags used in the templates")
 	listCmd.Flags().BoolVar(&tagsOnly, "list-tags", false, "alias for '--tags-only' flag")
 	listCmd.Flags().BoolVar(&tagsOnly, "show-tags", false, "alias for '--tags-only' flag")
+	listCmd.Flags().BoolVar(&listTree, "tree", false, "list templates as a tree of namespaces (or, with --tags-only, tags as a tree with template counts)")
+	listCmd.Flags().BoolVarP(&listLong, "long", "l", false, "list templates as a table with their tags, description, times and size")
+	listCmd.Flags().StringVar(&listSort, "sort", sortName, "order of the templates: "+strings.Join(listSorts, ", "))
+	listCmd.Flags().IntVarP(&listLimit, "limit", "n", 0, "only list the first N templates (after sorting)")
+	listCmd.Flags().StringSliceVar(&listFilters, "filter", []string{}, "comma separated list of globs to filter template names or namespaces, ie 'work/*'")
+	listCmd.Flags().BoolVar(&listSources, "sources", false, "show the template directory each template comes from")
 }
 
-func list() {
+func list() error {
+	if listTree && listSources {
+		return &usageError{err: fmt.Errorf("--tree and --sources can't be used together")}
+	}
+	if listTree && listLong {
+		return &usageError{err: fmt.Errorf("--tree and --long can't be used together")}
+	}
+	if !slices.Contains(listSorts, listSort) {
+		return &usageError{err: fmt.Errorf("invalid --sort '%s': must be one of: %s", listSort, strings.Join(listSorts, ", "))}
+	}
+	if listLimit < 0 {
+		return &usageError{err: fmt.Errorf("invalid --limit %d: must not be negative", listLimit)}
+	}
+
 	if tagsOnly {
-		err := listTemplateTags(viper.GetString("templatedir"))
-		if err != nil {
-			fmt.Printf("Call to list Clip template tags failed: %v\n", err)
-		}
-	} else {
-		err := listTemplates(viper.GetString("templatedir"))
-		if err != nil {
-			fmt.Printf("Call to list Clip templates failed: %v\n", err)
+		if err := listTemplateTags(templateResolver()); err != nil {
+			return fmt.Errorf("call to list Clip template tags failed: %w", err)
 		}
+		return nil
+	}
+
+	if err := listTemplates(templateResolver()); err != nil {
+		return fmt.Errorf("call to list Clip templates failed: %w", err)
 	}
+	return nil
 }
 
-func listTemplates(dir string) error {
+func listTemplates(resolver *helpers.Resolver) error {
 	var files []string
 
-	// walk the template directory and get the files
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if !info.IsDir() && (filepath.Ext(path) == ".yaml" || filepath.Ext(path) == ".yml") {
-			filenameWithoutExtension := strings.TrimSuffix(info.Name(), filepath.Ext(info.Name()))
-
-			// if `--tags` filter was provided, check if file contains one of the provided tags
-			if len(tags) > 0 {
-				for _, tag := range tags {
-					tmpl, err := helpers.LoadTemplateFile(filepath.Join(dir, info.Name()))
-					if err != nil {
-						return fmt.Errorf("couldn't load Clip template '%s' to check for tags: %w", filenameWithoutExtension, err)
-					}
-
-					if helpers.Contains(tmpl.Tags, tag) && !helpers.Contains(files, filenameWithoutExtension) {
-						files = append(files, filenameWithoutExtension)
-					}
+	// filtered by `--tags`/`--query` and `--filter`, if given
+	listed, err := selectTemplates(resolver, false)
+	if err != nil {
+		return err
+	}
+	listed, err = filterTemplateNames(listed, listFilters)
+	if err != nil {
+		return err
+	}
+
+	// templates are listed by name, so their files only need to be loaded
+	// for the table, the other orders, and --output. A template that can't
+	// be loaded is still listed with the details of its file.
+	entries := []templateOutput{}
+	if listLong || listSort != sortName || output != outputText {
+		stats := loadUsage()
+		for _, t := range listed {
+			info, _, err := newTemplateOutput(t, stats)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				if info.Name == "" {
+					continue
 				}
-			} else {
-				files = append(files, filenameWithoutExtension)
 			}
+			entries = append(entries, info)
 		}
-		return nil
-	})
+		sortTemplateOutputs(entries, listSort, stats, time.Now())
 
-	if err != nil {
-		return fmt.Errorf("failed to walk template directory: %w", err)
+		listed = listed[:0]
+		for _, e := range entries {
+			listed = append(listed, helpers.Template{Name: e.Name, Path: e.Path, Root: e.Directory})
+		}
+	}
+
+	if listLimit > 0 && len(listed) > listLimit {
+		listed = listed[:listLimit]
+		entries = entries[:min(len(entries), listLimit)]
+	}
+
+	for _, t := range listed {
+		files = append(files, t.Name)
+	}
+
+	// --tree and --sources only change the text output
+	if output != outputText {
+		return printOutput(entries, nil)
+	}
+
+	if listLong {
+		return printTemplateTable(os.Stdout, entries)
+	}
+
+	if listSources {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, t := range listed {
+			fmt.Fprintf(w, "%s\t%s\n", t.Name, t.Root)
+		}
+		return w.Flush()
+	}
+
+	if listTree {
+		printTemplateTree(os.Stdout, files)
+		return nil
 	}
 
 	for _, file := range files {
@@ -115,34 +216,310 @@ func listTemplates(dir string) error {
 	return nil
 }
 
-func listTemplateTags(dir string) error {
-	var tags []string
+// filterTemplateNames returns the templates whose names match any of globs,
+// or all of them if there are no globs. Like path.Match, `*` doesn't match
+// a `/`, but a glob that matches a namespace also matches everything in it,
+// so `work/*` (or `work`) matches `work/oncall/handoff`.
+func filterTemplateNames(templates []helpers.Template, globs []string) ([]helpers.Template, error) {
+	if len(globs) == 0 {
+		return templates, nil
+	}
 
-	// walk the template directory and get the files
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if !info.IsDir() && (filepath.Ext(path) == ".yaml" || filepath.Ext(path) == ".yml") {
-			tmpl, err := helpers.LoadTemplateFile(filepath.Join(dir, info.Name()))
-			if err != nil {
-				return fmt.Errorf("couldn't load Clip template to check for tags: %w", err)
+	for _, glob := range globs {
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, &usageError{err: fmt.Errorf("invalid --filter '%s': %w", glob, err)}
+		}
+	}
+
+	var filtered []helpers.Template
+	for _, t := range templates {
+		if slices.ContainsFunc(globs, func(glob string) bool {
+			return matchTemplateName(glob, t.Name)
+		}) {
+			filtered = append(filtered, t)
+		}
+	}
+
+	return filtered, nil
+}
+
+// matchTemplateName reports whether glob matches name or one of the
+// namespaces it's in
+func matchTemplateName(glob, name string) bool {
+	for i := range name {
+		if name[i] == '/' {
+			if ok, _ := path.Match(glob, name[:i]); ok {
+				return true
 			}
+		}
+	}
 
-			for _, tag := range tmpl.Tags {
-				if !helpers.Contains(tags, tag) {
-					tags = append(tags, tag)
+	ok, _ := path.Match(glob, name)
+	return ok
+}
+
+// sortTemplateOutputs sorts templates in the given order (see listSorts).
+// Ties, including templates that have never been used, are in name order.
+func sortTemplateOutputs(entries []templateOutput, order string, stats map[string]usage.Stats, now time.Time) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch order {
+		case sortModified:
+			if !a.Modified.Equal(b.Modified) {
+				return a.Modified.After(b.Modified)
+			}
+		case sortUsed:
+			if a.LastUsed == nil || b.LastUsed == nil {
+				if a.LastUsed != b.LastUsed {
+					return b.LastUsed == nil
 				}
+			} else if !a.LastUsed.Equal(*b.LastUsed) {
+				return a.LastUsed.After(*b.LastUsed)
+			}
+		case sortFrecency:
+			if fa, fb := stats[a.Name].Frecency(now), stats[b.Name].Frecency(now); fa != fb {
+				return fa > fb
 			}
 		}
-		return nil
+		return a.Name < b.Name
 	})
+}
+
+// printTemplateTable prints templates as a table for `list --long`, with
+// the template directory of each if --sources is set
+func printTemplateTable(w io.Writer, entries []templateOutput) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	header := "NAME\tTAGS\tDESCRIPTION\tMODIFIED\tLAST USED\tSIZE"
+	if listSources {
+		header += "\tDIRECTORY"
+	}
+	fmt.Fprintln(tw, header)
+
+	for _, e := range entries {
+		tags := strings.Join(e.Tags, ",")
+		if tags == "" {
+			tags = "-"
+		}
+
+		// only the first line of the description, shortened to fit
+		description, _, _ := strings.Cut(strings.TrimSpace(e.Description), "\n")
+		if r := []rune(description); len(r) > 50 {
+			description = string(r[:47]) + "..."
+		}
+		if description == "" {
+			description = "-"
+		}
+
+		lastUsed := "never"
+		if e.LastUsed != nil {
+			lastUsed = e.LastUsed.Local().Format("2006-01-02 15:04")
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s", e.Name, tags, description, e.Modified.Local().Format("2006-01-02 15:04"), lastUsed, formatSize(e.Size))
+		if listSources {
+			fmt.Fprintf(tw, "\t%s", e.Directory)
+		}
+		fmt.Fprintln(tw)
+	}
+
+	return tw.Flush()
+}
+
+// formatSize formats a file size in bytes for people, ie `812B` or `1.5K`
+func formatSize(size int64) string {
+	if size < 1024 {
+		return fmt.Sprintf("%dB", size)
+	}
+
+	value := float64(size) / 1024
+	for _, unit := range []string{"K", "M", "G"} {
+		if value < 1024 || unit == "G" {
+			return fmt.Sprintf("%.1f%s", value, unit)
+		}
+		value /= 1024
+	}
+
+	return ""
+}
+
+// printTemplateTree prints namespaced template names as a tree:
+//
+//	standup
+//	work/
+//	└── oncall/
+//	    ├── handoff
+//	    └── incident
+func printTemplateTree(w io.Writer, names []string) {
+	type node struct {
+		children map[string]*node
+		leaf     bool
+	}
+	root := &node{children: map[string]*node{}}
+	for _, name := range names {
+		n := root
+		for _, part := range strings.Split(name, "/") {
+			child, ok := n.children[part]
+			if !ok {
+				child = &node{children: map[string]*node{}}
+				n.children[part] = child
+			}
+			n = child
+		}
+		n.leaf = true
+	}
+
+	var walk func(n *node, prefix string, top bool)
+	walk = func(n *node, prefix string, top bool) {
+		keys := make([]string, 0, len(n.children))
+		for k := range n.children {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		// a template can share its name with a namespace (ie both
+		// `work.yml` and `work/`), in which case it gets its own line
+		type entry struct {
+			label string
+			child *node
+		}
+		var entries []entry
+		for _, k := range keys {
+			child := n.children[k]
+			if child.leaf {
+				entries = append(entries, entry{label: k})
+			}
+			if len(child.children) > 0 {
+				entries = append(entries, entry{label: k + "/", child: child})
+			}
+		}
+
+		for i, e := range entries {
+			branch, indent := "├── ", "│   "
+			if i == len(entries)-1 {
+				branch, indent = "└── ", "    "
+			}
+			if top {
+				branch, indent = "", ""
+			}
+
+			fmt.Fprintf(w, "%s%s%s\n", prefix, branch, e.label)
+			if e.child != nil {
+				walk(e.child, prefix+indent, false)
+			}
+		}
+	}
+	walk(root, "", true)
+}
+
+func listTemplateTags(resolver *helpers.Resolver) error {
+	var tags []string
+	counts := make(map[string]int) // templates per tag, including parent tags
+	direct := make(map[string]int) // templates per tag, without children
 
+	// only the tags of the templates matching `--tags`/`--query`, if given
+	templates, err := selectTemplates(resolver, false)
 	if err != nil {
-		return fmt.Errorf("failed to walk template directory: %w", err)
+		return err
+	}
+
+	for _, t := range templates {
+		tmpl, err := helpers.LoadTemplateFile(t.Path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping Clip template '%s', couldn't load it to check for tags: %v\n", t.Name, err)
+			continue
+		}
+
+		counted := make(map[string]bool) // the tags and their parents
+		has := make(map[string]bool)     // the tags themselves
+		for _, tag := range tmpl.Tags {
+			if tag == "" {
+				continue
+			}
+			if !helpers.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+			if !has[tag] {
+				has[tag] = true
+				direct[tag]++
+			}
+			for _, t := range append(helpers.ParentTags(tag), tag) {
+				if !counted[t] {
+					counted[t] = true
+					counts[t]++
+				}
+			}
+		}
 	}
 
 	sort.Strings(tags)
-	for _, tag := range tags {
-		fmt.Println(tag)
+
+	// the tree also has the parent tags that no template has itself
+	nodes := tags
+	if listTree {
+		nodes = make([]string, 0, len(counts))
+		for tag := range counts {
+			nodes = append(nodes, tag)
+		}
+		sort.Strings(nodes)
 	}
 
-	return nil
+	entries := []tagOutput{}
+	for _, tag := range nodes {
+		entries = append(entries, tagOutput{Name: tag, Templates: counts[tag], Direct: direct[tag]})
+	}
+
+	return printOutput(entries, func() error {
+		if listTree {
+			printTagTree(os.Stdout, counts)
+			return nil
+		}
+
+		for _, tag := range tags {
+			fmt.Println(tag)
+		}
+		return nil
+	})
+}
+
+// printTagTree prints hierarchical tags as a tree, with the number of
+// templates that have each tag (or one of its children):
+//
+//	golang (1)
+//	work (3)
+//	├── aws (2)
+//	└── k8s (1)
+func printTagTree(w io.Writer, counts map[string]int) {
+	children := make(map[string][]string)
+	for tag := range counts {
+		parent := ""
+		if parents := helpers.ParentTags(tag); len(parents) > 0 {
+			parent = parents[len(parents)-1]
+		}
+		children[parent] = append(children[parent], tag)
+	}
+
+	var walk func(parent, prefix string, top bool)
+	walk = func(parent, prefix string, top bool) {
+		tags := children[parent]
+		sort.Strings(tags)
+
+		for i, tag := range tags {
+			branch, indent := "├── ", "│   "
+			if i == len(tags)-1 {
+				branch, indent = "└── ", "    "
+			}
+			if top {
+				branch, indent = "", ""
+			}
+
+			label := tag
+			if parent != "" {
+				label = strings.TrimPrefix(tag, parent+"/")
+			}
+			fmt.Fprintf(w, "%s%s%s (%d)\n", prefix, branch, label, counts[tag])
+			walk(tag, prefix+indent, false)
+		}
+	}
+	walk("", "", true)
 }
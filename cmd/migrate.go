@@ -0,0 +1,170 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/tjhop/clip/helpers"
+)
+
+var migrateDryRun bool
+
+var migrateConfigCmd = &cobra.Command{
+	Use:   "migrate-config",
+	Short: "Move a legacy $HOME/.clip.yml setup to the XDG base directories",
+	Long: `Move a legacy setup, with the config file at $HOME/.clip.yml, to the XDG base
+directories:
+
+  $HOME/.clip.yml           -> $XDG_CONFIG_HOME/clip/config.yml
+  $HOME/clip/               -> $XDG_DATA_HOME/clip/templates/
+  $HOME/.clip_history.json  -> $XDG_STATE_HOME/clip/history.json
+  $HOME/.clip_clipboard     -> $XDG_STATE_HOME/clip/clipboard
+  $HOME/.clip_usage.json    -> $XDG_STATE_HOME/clip/usage.json
+
+Files that were moved to a custom location are left where they are, and the
+config file is updated to point at the files that were moved.`,
+	Args: usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := migrateConfig(); err != nil {
+			return fmt.Errorf("failed to migrate Clip config: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateConfigCmd)
+
+	// command Line flags
+	migrateConfigCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "print what would be moved without changing anything")
+}
+
+// migration is a file or directory moved by migrate-config
+type migration struct {
+	what     string
+	from, to string
+
+	// key is the config key that points at the file, if it's set
+	key string
+}
+
+func migrateConfig() error {
+	home, err := homedir.Dir()
+	if err != nil {
+		return fmt.Errorf("%w: couldn't find home directory: %w", helpers.ErrInvalidConfig, err)
+	}
+	legacy, xdg := legacyPaths(home), xdgPaths(home)
+
+	if _, err := os.Stat(legacy.ConfigFile); os.IsNotExist(err) {
+		return fmt.Errorf("no legacy config file at %s, nothing to migrate", legacy.ConfigFile)
+	}
+	if _, err := os.Stat(xdg.ConfigFile); err == nil {
+		return fmt.Errorf("config file %s already exists; merge %s into it by hand", xdg.ConfigFile, legacy.ConfigFile)
+	}
+
+	// read the legacy config on its own, in case --config points elsewhere
+	v := viper.New()
+	v.SetConfigType("yaml")
+	v.SetConfigFile(legacy.ConfigFile)
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("%w: failed to read config file '%s': %w", helpers.ErrInvalidConfig, legacy.ConfigFile, err)
+	}
+
+	var migrations []migration
+	for _, m := range []migration{
+		{what: "template directory", from: legacy.TemplateDir, to: xdg.TemplateDir, key: "templatedir"},
+		{what: "history file", from: legacy.HistoryFile, to: xdg.HistoryFile, key: "history.file"},
+		{what: "clipboard file", from: legacy.ClipboardFile, to: xdg.ClipboardFile, key: "clipboard.file"},
+		{what: "usage file", from: legacy.UsageFile, to: xdg.UsageFile, key: "usage.file"},
+	} {
+		// only files in their legacy default location are moved
+		if configured := v.GetString(m.key); configured != "" {
+			if configured, err = homedir.Expand(configured); err != nil || filepath.Clean(configured) != m.from {
+				continue
+			}
+		}
+		if !v.IsSet(m.key) {
+			m.key = ""
+		}
+
+		if _, err := os.Stat(m.from); os.IsNotExist(err) {
+			continue
+		}
+		if _, err := os.Stat(m.to); err == nil {
+			fmt.Fprintf(os.Stderr, "Leaving %s at %s: %s already exists\n", m.what, m.from, m.to)
+			continue
+		}
+
+		migrations = append(migrations, m)
+	}
+	migrations = append(migrations, migration{what: "config file", from: legacy.ConfigFile, to: xdg.ConfigFile})
+
+	moved := make([]migrationOutput, 0, len(migrations))
+	for _, m := range migrations {
+		if migrateDryRun {
+			if output == outputText {
+				fmt.Printf("Would move %s %s to %s\n", m.what, m.from, m.to)
+			}
+			moved = append(moved, migrationOutput{What: m.what, From: m.from, To: m.to, DryRun: true})
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(m.to), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", m.what, err)
+		}
+		if err := os.Rename(m.from, m.to); err != nil {
+			return fmt.Errorf("failed to move %s (move it by hand if it's on another filesystem): %w", m.what, err)
+		}
+		if output == outputText {
+			fmt.Printf("Moved %s %s to %s\n", m.what, m.from, m.to)
+		}
+		moved = append(moved, migrationOutput{What: m.what, From: m.from, To: m.to})
+	}
+
+	if migrateDryRun {
+		return printOutput(moved, func() error { return nil })
+	}
+
+	// point the keys in the (moved) config file at the new locations
+	err = editConfigFile(xdg.ConfigFile, func(doc *helpers.Document) error {
+		for _, m := range migrations {
+			if m.key == "" {
+				continue
+			}
+			if err := doc.Set(m.key, m.to); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update config file: %w", err)
+	}
+
+	return printOutput(moved, func() error { return nil })
+}
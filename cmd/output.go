@@ -0,0 +1,241 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/tjhop/clip/helpers"
+	"github.com/tjhop/clip/usage"
+)
+
+// Output formats for the --output flag. text is meant for people, while json
+// and yaml print the structs below, which are part of the CLI's interface
+// (and documented in the README), so only add fields to them.
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml"
+)
+
+var outputFormats = []string{outputText, outputJSON, outputYAML}
+
+// outputFormat is the --output flag. It's checked when the flag is parsed,
+// so a typo is a usage error even for commands that only print text.
+type outputFormat string
+
+func (f *outputFormat) String() string { return string(*f) }
+
+func (f *outputFormat) Set(s string) error {
+	s = strings.ToLower(s)
+	if !slices.Contains(outputFormats, s) {
+		return fmt.Errorf("must be one of: %s", strings.Join(outputFormats, ", "))
+	}
+	*f = outputFormat(s)
+	return nil
+}
+
+func (f *outputFormat) Type() string { return "format" }
+
+var output = outputFormat(outputText) // --output
+
+// printOutput prints v as JSON or YAML if that's the selected output format,
+// and calls text to print it for people otherwise
+func printOutput(v interface{}, text func() error) error {
+	switch output {
+	case outputJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case outputYAML:
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	default:
+		return text()
+	}
+}
+
+// templateOutput describes a template in `list` and `which`. LastUsed is
+// null for templates that have never been copied.
+type templateOutput struct {
+	Name        string     `json:"name" yaml:"name"`
+	Path        string     `json:"path" yaml:"path"`
+	Directory   string     `json:"directory" yaml:"directory"`
+	Tags        []string   `json:"tags" yaml:"tags"`
+	Description string     `json:"description" yaml:"description"`
+	Modified    time.Time  `json:"modified" yaml:"modified"`
+	Size        int64      `json:"size" yaml:"size"`
+	LastUsed    *time.Time `json:"last_used" yaml:"last_used"`
+	Uses        int        `json:"uses" yaml:"uses"`
+}
+
+// showOutput is a template in `show`, with its raw file and parsed contents
+type showOutput struct {
+	templateOutput `yaml:",inline"`
+
+	Vars    map[string]interface{} `json:"vars" yaml:"vars"`
+	Text    string                 `json:"text" yaml:"text"`
+	Content string                 `json:"content" yaml:"content"`
+}
+
+// whichOutput is the template a name resolves to and the ones it shadows
+type whichOutput struct {
+	templateOutput `yaml:",inline"`
+
+	// ReadOnly is set for templates in the system template directories
+	ReadOnly bool     `json:"read_only" yaml:"read_only"`
+	Shadowed []string `json:"shadowed" yaml:"shadowed"`
+}
+
+// tagOutput is a tag in `list --tags-only`. Templates counts the templates
+// with the tag or one of its children, and Direct the ones with the tag
+// itself (0 for parent tags that are only listed with --tree).
+type tagOutput struct {
+	Name      string `json:"name" yaml:"name"`
+	Templates int    `json:"templates" yaml:"templates"`
+	Direct    int    `json:"direct" yaml:"direct"`
+}
+
+// clipboardOutput is the clipboard contents in `paste`
+type clipboardOutput struct {
+	Content string `json:"content" yaml:"content"`
+}
+
+// versionOutput is the build info in `version`
+type versionOutput struct {
+	Version   string `json:"version" yaml:"version"`
+	Commit    string `json:"commit" yaml:"commit"`
+	BuildDate string `json:"build_date" yaml:"build_date"`
+}
+
+// historyOutput is a clipboard history entry in `history`
+type historyOutput struct {
+	Index   int       `json:"index" yaml:"index"`
+	Time    time.Time `json:"time" yaml:"time"`
+	Source  string    `json:"source" yaml:"source"`
+	Content string    `json:"content" yaml:"content"`
+}
+
+// historyRemovedOutput is the number of entries removed by `history clear`
+// and `history prune`
+type historyRemovedOutput struct {
+	Removed int `json:"removed" yaml:"removed"`
+}
+
+// templateChangeOutput is a template created, removed or renamed by
+// `create`, `remove` and `rename`. Renamed templates also have the name and
+// path they had before.
+type templateChangeOutput struct {
+	Name    string `json:"name" yaml:"name"`
+	Path    string `json:"path" yaml:"path"`
+	OldName string `json:"old_name,omitempty" yaml:"old_name,omitempty"`
+	OldPath string `json:"old_path,omitempty" yaml:"old_path,omitempty"`
+}
+
+// tagChangeOutput is a template whose tags were changed by `tag add`,
+// `remove`, `rename` or `merge`
+type tagChangeOutput struct {
+	Name    string   `json:"name" yaml:"name"`
+	Path    string   `json:"path" yaml:"path"`
+	Added   []string `json:"added" yaml:"added"`
+	Removed []string `json:"removed" yaml:"removed"`
+	DryRun  bool     `json:"dry_run" yaml:"dry_run"`
+}
+
+// profileOutput is a configuration profile in `profile list`
+type profileOutput struct {
+	Name   string `json:"name" yaml:"name"`
+	Active bool   `json:"active" yaml:"active"`
+}
+
+// defaultProfileOutput is the default profile set by `profile use`. Profile
+// is empty if the default was cleared with --none.
+type defaultProfileOutput struct {
+	Profile string `json:"profile" yaml:"profile"`
+}
+
+// configPathOutput is the config file in `config path` and `config validate`
+type configPathOutput struct {
+	Path  string `json:"path" yaml:"path"`
+	Valid *bool  `json:"valid,omitempty" yaml:"valid,omitempty"`
+}
+
+// configKeyOutput is a config key changed by `config set` and `config unset`.
+// Value is left out for unset keys.
+type configKeyOutput struct {
+	Key   string      `json:"key" yaml:"key"`
+	Value interface{} `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// migrationOutput is a file or directory moved by `migrate-config`
+type migrationOutput struct {
+	What   string `json:"what" yaml:"what"`
+	From   string `json:"from" yaml:"from"`
+	To     string `json:"to" yaml:"to"`
+	DryRun bool   `json:"dry_run" yaml:"dry_run"`
+}
+
+// newTemplateOutput loads the details of a template for the output structs.
+// stats is the usage of every template, see loadUsage. If the template file
+// can't be parsed, the error is returned along with the details that don't
+// need it (name, path, size, times), so it can still be listed.
+func newTemplateOutput(t helpers.Template, stats map[string]usage.Stats) (templateOutput, helpers.TemplateFile, error) {
+	info, err := os.Stat(t.Path)
+	if err != nil {
+		return templateOutput{}, helpers.TemplateFile{}, fmt.Errorf("failed to read template file: %w", err)
+	}
+
+	out := templateOutput{
+		Name:      t.Name,
+		Path:      t.Path,
+		Directory: t.Root,
+		Tags:      []string{},
+		Modified:  info.ModTime(),
+		Size:      info.Size(),
+	}
+	if st, ok := stats[t.Name]; ok && st.Count > 0 {
+		lastUsed := st.LastUsed
+		out.LastUsed, out.Uses = &lastUsed, st.Count
+	}
+
+	tmpl, err := helpers.LoadTemplateFile(t.Path)
+	if err != nil {
+		return out, helpers.TemplateFile{}, fmt.Errorf("couldn't load Clip template '%s': %w", t.Name, err)
+	}
+
+	if tmpl.Tags != nil {
+		out.Tags = tmpl.Tags
+	}
+	out.Description = tmpl.Description
+
+	return out, tmpl, nil
+}
@@ -23,7 +23,6 @@ package cmd
 import (
 	"fmt"
 
-	"github.com/atotto/clipboard"
 	"github.com/spf13/cobra"
 )
 
@@ -31,11 +30,11 @@ var pasteCmd = &cobra.Command{
 	Use:     "paste",
 	Aliases: []string{"out", "print"},
 	Short:   "Print clipboard contents to stdout",
-	Run: func(cmd *cobra.Command, args []string) {
-		err := writeClipboardToStdout()
-		if err != nil {
-			fmt.Printf("Call to print clipboard contents failed: %v\n", err)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := writeClipboardToStdout(); err != nil {
+			return fmt.Errorf("call to print clipboard contents failed: %w", err)
 		}
+		return nil
 	},
 }
 
@@ -44,11 +43,18 @@ func init() {
 }
 
 func writeClipboardToStdout() error {
-	str, err := clipboard.ReadAll()
+	cb, err := getClipboard()
 	if err != nil {
-		return fmt.Errorf("failed to dump clipboard contents to variable: %w", err)
+		return fmt.Errorf("failed to open clipboard: %w", err)
 	}
 
-	fmt.Println(str)
-	return nil
+	str, err := cb.ReadAll()
+	if err != nil {
+		return fmt.Errorf("failed to dump clipboard contents to variable: %w", clipboardError(err))
+	}
+
+	return printOutput(clipboardOutput{Content: str}, func() error {
+		fmt.Println(str)
+		return nil
+	})
 }
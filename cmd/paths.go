@@ -0,0 +1,114 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// clipPaths are the default locations of the files Clip uses
+type clipPaths struct {
+	ConfigFile    string
+	TemplateDir   string
+	HistoryFile   string
+	ClipboardFile string
+	UsageFile     string
+
+	// Legacy is set for setups that use `~/.clip.yml` rather than the XDG
+	// base directories
+	Legacy bool
+}
+
+// xdgDir returns the XDG base directory named by env, or fallback (relative
+// to home) if it's unset. Relative paths are invalid per the spec and are
+// ignored.
+func xdgDir(env, home, fallback string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir
+	}
+
+	return filepath.Join(home, fallback)
+}
+
+// xdgPaths returns the locations of Clip's files in the XDG base
+// directories, which are used for new installs
+func xdgPaths(home string) clipPaths {
+	stateDir := filepath.Join(xdgDir("XDG_STATE_HOME", home, ".local/state"), "clip")
+
+	return clipPaths{
+		ConfigFile:    filepath.Join(xdgDir("XDG_CONFIG_HOME", home, ".config"), "clip", "config.yml"),
+		TemplateDir:   filepath.Join(xdgDir("XDG_DATA_HOME", home, ".local/share"), "clip", "templates"),
+		HistoryFile:   filepath.Join(stateDir, "history.json"),
+		ClipboardFile: filepath.Join(stateDir, "clipboard"),
+		UsageFile:     filepath.Join(stateDir, "usage.json"),
+	}
+}
+
+// legacyPaths returns the locations Clip used before it supported the XDG
+// base directories, with everything directly in the home directory
+func legacyPaths(home string) clipPaths {
+	return clipPaths{
+		ConfigFile:    filepath.Join(home, ".clip.yml"),
+		TemplateDir:   filepath.Join(home, "clip"),
+		HistoryFile:   filepath.Join(home, ".clip_history.json"),
+		ClipboardFile: filepath.Join(home, ".clip_clipboard"),
+		UsageFile:     filepath.Join(home, ".clip_usage.json"),
+		Legacy:        true,
+	}
+}
+
+// configSearchPath returns the locations of the config file, in the order
+// they're searched
+func configSearchPath(home string) []string {
+	return []string{
+		xdgPaths(home).ConfigFile,
+		filepath.Join(home, ".config", "clip", "config.yml"),
+		legacyPaths(home).ConfigFile,
+	}
+}
+
+// defaultPaths returns the locations of Clip's files. The config file is
+// cfgFile if set, otherwise the first config file found in the search
+// path. Legacy setups keep their files in the home directory, everything
+// else (including new installs) uses the XDG base directories.
+func defaultPaths(home, cfgFile string) clipPaths {
+	if cfgFile == "" {
+		for _, filename := range configSearchPath(home) {
+			if _, err := os.Stat(filename); err == nil {
+				cfgFile = filename
+				break
+			}
+		}
+	}
+
+	legacy := legacyPaths(home)
+	if cfgFile != "" && filepath.Clean(cfgFile) == legacy.ConfigFile {
+		return legacy
+	}
+
+	paths := xdgPaths(home)
+	if cfgFile != "" {
+		paths.ConfigFile = cfgFile
+	}
+
+	return paths
+}
@@ -0,0 +1,253 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tjhop/clip/helpers"
+)
+
+var (
+	profileName   string // profile selected with --profile
+	activeProfile string // profile applied by initClip, if any
+	profileNone   bool
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "List, show and select configuration profiles",
+	Long: `Configuration profiles are named sets of settings in the 'profiles' section of
+the config file. The selected profile is merged over the top-level settings.
+
+A profile is selected with the --profile flag, the CLIP_PROFILE environment
+variable, or the 'profile' key in the config file (set by 'clip profile use'),
+in that order.
+
+Example:
+  clip profile list
+  clip profile show work
+  clip profile use work
+  clip --profile personal list`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return profileListCmd.RunE(cmd, args)
+	},
+}
+
+var profileListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List configuration profiles (the active profile is marked with *)",
+	Args:    usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := []profileOutput{}
+		for _, name := range profileNames() {
+			out = append(out, profileOutput{Name: name, Active: name == activeProfile})
+		}
+
+		return printOutput(out, func() error {
+			for _, p := range out {
+				marker := " "
+				if p.Active {
+					marker = "*"
+				}
+				fmt.Printf("%s %s\n", marker, p.Name)
+			}
+			return nil
+		})
+	},
+}
+
+var profileShowCmd = &cobra.Command{
+	Use:   "show [profile]",
+	Short: "Print the settings of a profile (default is the active profile)",
+	Args:  usageArgs(cobra.MaximumNArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := activeProfile
+		if len(args) > 0 {
+			name = args[0]
+		}
+		if name == "" {
+			return fmt.Errorf("no profile is active")
+		}
+
+		settings, err := profileSettings(name)
+		if err != nil {
+			return err
+		}
+
+		return printOutput(settings, func() error {
+			out, err := yaml.Marshal(settings)
+			if err != nil {
+				return fmt.Errorf("failed to marshal profile '%s': %w", name, err)
+			}
+			fmt.Print(string(out))
+			return nil
+		})
+	},
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:   "use <profile>",
+	Short: "Set the profile used by default",
+	Long: `Set the profile used by default by writing the 'profile' key of the config file.
+The --profile flag and CLIP_PROFILE environment variable still take precedence.`,
+	Args: usageArgs(func(cmd *cobra.Command, args []string) error {
+		if profileNone {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	}),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := ""
+		if !profileNone {
+			name = args[0]
+			if _, err := profileSettings(name); err != nil {
+				return err
+			}
+		}
+
+		err := editConfigFile(viper.ConfigFileUsed(), func(doc *helpers.Document) error {
+			if name == "" {
+				doc.Delete("profile")
+				return nil
+			}
+			return doc.Set("profile", name)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set default profile: %w", err)
+		}
+
+		return printOutput(defaultProfileOutput{Profile: name}, func() error {
+			if name == "" {
+				fmt.Println("Default profile cleared")
+			} else {
+				fmt.Printf("Default profile set to '%s'\n", name)
+			}
+			return nil
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+	profileCmd.AddCommand(profileListCmd, profileShowCmd, profileUseCmd)
+
+	// command Line flags
+	profileUseCmd.Flags().BoolVar(&profileNone, "none", false, "clear the default profile")
+}
+
+// profileNames returns the names of the profiles in the config file
+func profileNames() []string {
+	profiles := viper.GetStringMap("profiles")
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// profileSettings returns the settings of the named profile
+func profileSettings(name string) (map[string]interface{}, error) {
+	settings, ok := viper.GetStringMap("profiles")[strings.ToLower(name)]
+	if !ok {
+		available := strings.Join(profileNames(), ", ")
+		if available == "" {
+			available = "none defined"
+		}
+		return nil, fmt.Errorf("%w: unknown profile '%s' (available: %s)", helpers.ErrInvalidConfig, name, available)
+	}
+
+	if settings == nil {
+		return map[string]interface{}{}, nil
+	}
+	m, ok := settings.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: profile '%s' must be a mapping of settings", helpers.ErrInvalidConfig, name)
+	}
+
+	return m, nil
+}
+
+// isConfigCommand reports whether cmd is help or one of the `profile` and
+// `config` commands, which have to work with a broken profile selected so
+// it can be fixed
+func isConfigCommand(cmd *cobra.Command) bool {
+	if f := cmd.Flags().Lookup("help"); f != nil && f.Changed {
+		return true
+	}
+
+	// compare the top level command by name; referring to configCmd here
+	// would be an initialization cycle, as it runs initClip
+	for c := cmd; c.HasParent(); c = c.Parent() {
+		if !c.Parent().HasParent() {
+			return c.Name() == "profile" || c.Name() == "config" || c.Name() == "help"
+		}
+	}
+
+	return false
+}
+
+// applyProfile merges the selected profile, if any, over the top-level
+// settings of the config file
+func applyProfile() error {
+	name := viper.GetString("profile")
+	if name == "" {
+		return nil
+	}
+
+	settings, err := profileSettings(name)
+	if err != nil {
+		return err
+	}
+
+	overrides := make(map[string]interface{}, len(settings))
+	for k, v := range settings {
+		// profiles can't select or define other profiles
+		if k != "profile" && k != "profiles" {
+			overrides[k] = v
+		}
+	}
+
+	// a profile that sets a single template directory replaces a list of
+	// them at the top level, and vice versa
+	if _, ok := overrides["templatedir"]; ok && viper.InConfig("templatedirs") {
+		if _, ok := overrides["templatedirs"]; !ok {
+			overrides["templatedirs"] = []interface{}{overrides["templatedir"]}
+		}
+	}
+
+	if err := viper.MergeConfigMap(overrides); err != nil {
+		return errors.Join(helpers.ErrInvalidConfig, err)
+	}
+	activeProfile = strings.ToLower(name)
+
+	return nil
+}
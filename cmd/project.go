@@ -0,0 +1,116 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tjhop/clip/helpers"
+)
+
+// projectDirName is the directory (or, with a .yml extension, the config
+// file) that marks a project with its own Clip templates and vars
+const projectDirName = ".clip"
+
+// project is a directory with project-local Clip templates and/or config
+type project struct {
+	// Dir is the directory with the `.clip/` directory or `.clip.yml`
+	Dir string
+
+	// ConfigFile is `.clip/config.yml` or `.clip.yml`, if either exists
+	ConfigFile string
+
+	// TemplateDir is `.clip/templates`, if it exists
+	TemplateDir string
+}
+
+// findProject walks up from dir looking for a `.clip/` directory or a
+// `.clip.yml` file, stopping before stop (the home directory, which has
+// the user's own config) and at the root of the filesystem
+func findProject(dir, stop string) (project, bool) {
+	stop = filepath.Clean(stop)
+	for dir = filepath.Clean(dir); dir != stop; dir = filepath.Dir(dir) {
+		var p project
+
+		projectDir := filepath.Join(dir, projectDirName)
+		if info, err := os.Stat(projectDir); err == nil && info.IsDir() {
+			if isFile(filepath.Join(projectDir, "config.yml")) {
+				p.ConfigFile = filepath.Join(projectDir, "config.yml")
+			}
+			if info, err := os.Stat(filepath.Join(projectDir, "templates")); err == nil && info.IsDir() {
+				p.TemplateDir = filepath.Join(projectDir, "templates")
+			}
+		}
+		if p.ConfigFile == "" && isFile(projectDir+".yml") {
+			p.ConfigFile = projectDir + ".yml"
+		}
+
+		if p.ConfigFile != "" || p.TemplateDir != "" {
+			p.Dir = dir
+			return p, true
+		}
+
+		if parent := filepath.Dir(dir); parent == dir {
+			break
+		}
+	}
+
+	return project{}, false
+}
+
+// loadProjectConfig merges the vars from the config file of a project on
+// top of the user's config. Other settings are ignored, so a checked out
+// repo can't change the editor or add watch hooks that run commands.
+func loadProjectConfig(p project) error {
+	if p.ConfigFile == "" {
+		return nil
+	}
+
+	buf, err := os.ReadFile(p.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("%w: failed to read project config file '%s': %w", helpers.ErrInvalidConfig, p.ConfigFile, err)
+	}
+
+	var cfg struct {
+		Vars map[string]interface{} `yaml:"vars"`
+	}
+	if err := yaml.Unmarshal(buf, &cfg); err != nil {
+		return fmt.Errorf("%w: failed to parse project config file '%s': %w", helpers.ErrInvalidConfig, p.ConfigFile, err)
+	}
+
+	if len(cfg.Vars) > 0 {
+		if err := viper.MergeConfigMap(map[string]interface{}{"vars": cfg.Vars}); err != nil {
+			return fmt.Errorf("%w: failed to merge project config file '%s': %w", helpers.ErrInvalidConfig, p.ConfigFile, err)
+		}
+	}
+
+	return nil
+}
+
+func isFile(filename string) bool {
+	info, err := os.Stat(filename)
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,149 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/tjhop/clip/helpers"
+)
+
+// errNotInteractive is returned when variables need to be prompted for but
+// there's no terminal to prompt on
+var errNotInteractive = errors.New("not running in an interactive terminal")
+
+// promptForVars asks for the value of each variable on the terminal. Enum
+// variables are offered as a numbered menu, secret variables are read
+// without echo, and defaults are used when the answer is left empty.
+func promptForVars(names []string, schema helpers.Schema) (map[string]interface{}, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, fmt.Errorf("%w: %w", &helpers.MissingVarsError{Names: names}, errNotInteractive)
+	}
+
+	// stdin is read unbuffered, a byte at a time, so nothing typed ahead is
+	// swallowed by a buffer before term.ReadPassword reads the same fd
+	in := os.Stdin
+	answers := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		spec := schema[name]
+		for {
+			answer, err := promptForVar(in, os.Stderr, name, spec)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read value for variable '%s': %w", name, err)
+			}
+
+			if answer == "" && spec.Default == nil {
+				if spec.Required {
+					fmt.Fprintf(os.Stderr, "A value for '%s' is required\n", name)
+					continue
+				}
+				answers[name] = answer
+				break
+			}
+			if answer == "" {
+				answers[name] = spec.Default
+				break
+			}
+
+			if _, err := spec.Check(answer); err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid value: %v\n", &helpers.VarError{Name: name, Reason: err.Error()})
+				continue
+			}
+			answers[name] = answer
+			break
+		}
+	}
+
+	return answers, nil
+}
+
+// promptForVar reads a single answer, returning an empty string when the
+// default should be used
+func promptForVar(in io.Reader, out io.Writer, name string, spec helpers.VarSpec) (string, error) {
+	label := name
+	if spec.Description != "" {
+		label = fmt.Sprintf("%s (%s)", name, spec.Description)
+	}
+	def := ""
+	if spec.Default != nil {
+		def = fmt.Sprintf(" [%v]", spec.Default)
+	}
+
+	if len(spec.Enum) > 0 {
+		fmt.Fprintf(out, "%s:\n", label)
+		for i, choice := range spec.Enum {
+			fmt.Fprintf(out, "  %d) %s\n", i+1, choice)
+		}
+		fmt.Fprintf(out, "Choice%s: ", def)
+
+		answer, err := readLine(in)
+		if err != nil {
+			return "", err
+		}
+		// accept either the number or the value of the choice
+		if i, err := strconv.Atoi(answer); err == nil && i >= 1 && i <= len(spec.Enum) {
+			return spec.Enum[i-1], nil
+		}
+		return answer, nil
+	}
+
+	fmt.Fprintf(out, "%s%s: ", label, def)
+	if spec.Secret {
+		buf, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(out)
+		if err != nil {
+			return "", err
+		}
+		return string(buf), nil
+	}
+
+	return readLine(in)
+}
+
+// readLine reads a line from in without reading past its end
+func readLine(in io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := in.Read(b)
+		if n > 0 {
+			if b[0] == '\n' {
+				break
+			}
+			line = append(line, b[0])
+		}
+		if err == io.EOF && len(line) > 0 {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return strings.TrimRight(string(line), "\r"), nil
+}
@@ -24,10 +24,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 // removeCmd represents the remove command
@@ -36,13 +34,12 @@ var removeCmd = &cobra.Command{
 	Aliases: []string{"delete"},
 	Short:   "Remove a Clip template",
 	Long:    `Delete a Clip template from your template folder`,
-	Args:    cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		templateFilename := filepath.Join(viper.GetString("templatedir"), os.Args[len(os.Args)-1]+".yml")
-		err := removeTemplateFile(templateFilename)
-		if err != nil {
-			fmt.Printf("Call to remove Clip template failed: %v\n", err)
+	Args:    usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := removeTemplateFile(args[0]); err != nil {
+			return fmt.Errorf("call to remove Clip template failed: %w", err)
 		}
+		return nil
 	},
 }
 
@@ -50,18 +47,24 @@ func init() {
 	rootCmd.AddCommand(removeCmd)
 }
 
-func removeTemplateFile(filename string) error {
+func removeTemplateFile(name string) error {
 	// check if template even exists
-	if _, err := os.Stat(filename); os.IsNotExist(err) {
-		fmt.Printf("Couldn't find a Clip template with the name: '%s'\n", strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)))
-	} else {
-		err := os.Remove(filename)
-		if err != nil {
-			return fmt.Errorf("failed to remove Clip template file: %w", err)
-		}
+	resolver := templateResolver()
+	t, err := resolver.ResolveWritable(name)
+	if err != nil {
+		return err
+	}
 
-		fmt.Printf("Clip template '%s' removed\n", strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)))
+	err = os.Remove(t.Path)
+	if err != nil {
+		return fmt.Errorf("failed to remove Clip template file: %w", err)
 	}
 
-	return nil
+	resolver.RemoveEmptyNamespaces(filepath.Dir(t.Path))
+	removeUsage(t.Name)
+
+	return printOutput(templateChangeOutput{Name: t.Name, Path: t.Path}, func() error {
+		fmt.Printf("Clip template '%s' removed\n", t.Name)
+		return nil
+	})
 }
@@ -27,7 +27,6 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 // renameCmd represents the rename command
@@ -37,14 +36,12 @@ var renameCmd = &cobra.Command{
 	Short:   "Rename a Clip template",
 	Long: `Rename an existing Clip template. The Clip template must already exist,
 and the new name must be available.`,
-	Args: cobra.ExactArgs(2),
-	Run: func(cmd *cobra.Command, args []string) {
-		sourceTemplateFilename := filepath.Join(viper.GetString("templatedir"), os.Args[len(os.Args)-2]+".yml")
-		destinationTemplateFilename := filepath.Join(viper.GetString("templatedir"), os.Args[len(os.Args)-1]+".yml")
-		err := renameTemplateFile(sourceTemplateFilename, destinationTemplateFilename)
-		if err != nil {
-			fmt.Printf("Call to rename template failed: %v\n", err)
+	Args: usageArgs(cobra.ExactArgs(2)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := renameTemplateFile(args[0], args[1]); err != nil {
+			return fmt.Errorf("call to rename template failed: %w", err)
 		}
+		return nil
 	},
 }
 
@@ -52,21 +49,40 @@ func init() {
 	rootCmd.AddCommand(renameCmd)
 }
 
-func renameTemplateFile(sourceFilename, destinationFilename string) error {
+func renameTemplateFile(sourceName, destinationName string) error {
 	// check to ensure source template exists
-	if _, err := os.Stat(sourceFilename); os.IsNotExist(err) {
-		fmt.Printf("Couldn't find a Clip template with the name: '%s'\n", strings.TrimSuffix(filepath.Base(sourceFilename), filepath.Ext(sourceFilename)))
+	resolver := templateResolver()
+	source, err := resolver.ResolveWritable(sourceName)
+	if err != nil {
+		return err
+	}
+
+	// check to ensure destination template does not exist. templates are
+	// renamed within the template directory they're in.
+	destination, err := resolver.NewIn(source, destinationName)
+	if err != nil {
+		return fmt.Errorf("no action taken: %w", err)
 	}
 
-	// check to ensure destination template does not exist
-	if _, err := os.Stat(destinationFilename); err == nil {
-		fmt.Printf("No action taken: Found an existing Clip template with the name: '%s'\n", strings.TrimSuffix(filepath.Base(destinationFilename), filepath.Ext(destinationFilename)))
+	// keep the extension of the source template
+	destination.Path = strings.TrimSuffix(destination.Path, filepath.Ext(destination.Path)) + filepath.Ext(source.Path)
+
+	err = os.MkdirAll(filepath.Dir(destination.Path), 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create template directory: %w", err)
 	}
 
-	err := os.Rename(sourceFilename, destinationFilename)
+	err = os.Rename(source.Path, destination.Path)
 	if err != nil {
 		return fmt.Errorf("failed to rename clip template file: %w", err)
 	}
+	resolver.RemoveEmptyNamespaces(filepath.Dir(source.Path))
+	renameUsage(source.Name, destination.Name)
 
-	return nil
+	return printOutput(templateChangeOutput{
+		Name:    destination.Name,
+		Path:    destination.Path,
+		OldName: source.Name,
+		OldPath: source.Path,
+	}, func() error { return nil })
 }
@@ -21,15 +21,18 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/tjhop/clip/clipboard"
 	"github.com/tjhop/clip/helpers"
 )
 
@@ -38,6 +41,8 @@ var (
 	cfgFile     string // location of config file
 	templateDir string // location of template directory
 	showBuild   bool   // whether or not to print version info
+	backend     string // clipboard backend to use
+	noLocal     bool   // whether to skip project-local templates and config
 )
 
 // rootCmd is the bare `clip` command that cobra executes
@@ -46,100 +51,190 @@ var rootCmd = &cobra.Command{
 	Short: "Golang template and clipboard editor",
 	Long: `Clip is a CLI tool to build and manage templated snippets and
 interact with the systems's clipboard`,
-	Args: cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	Args:               usageArgs(cobra.MaximumNArgs(1)),
+	FParseErrWhitelist: cobra.FParseErrWhitelist{UnknownFlags: true}, // per-template flags, see `copy`
+	// errors are printed by Execute, which also picks the exit code
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	// initialize clip before running any command
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return initClip(cmd)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// unknown flags are only allowed after a template name
+		rawArgs, err := commandArgs(cmd)
+		if err != nil {
+			return err
+		}
+		if err := checkFlagsBeforeTemplate(cmd, rawArgs); err != nil {
+			return err
+		}
+
 		if showBuild {
-			versionCmd.Run(cmd, args)
-		} else {
-			if len(args) == 0 {
-				// If no subcommand is provided, run `clip list` by default
-				listCmd.Run(cmd, args)
-			} else if len(args) == 1 {
-				// If no subcommand is provided but a command line arg is
-				// provided, then run `clip copy $arg` by default
-				copyCmd.Run(cmd, args)
-			}
+			return versionCmd.RunE(cmd, args)
+		}
+
+		if len(args) == 0 {
+			// If no subcommand is provided, run `clip list` by default
+			return listCmd.RunE(cmd, args)
 		}
+
+		// If no subcommand is provided but a command line arg is
+		// provided, then run `clip copy $arg` by default
+		return copyCmd.RunE(cmd, args)
 	},
 }
 
+// Execute runs clip, printing any error to stderr and exiting with the exit
+// code for it (see errors.go)
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+
+		var tmplErr *helpers.TemplateError
+		if errors.As(err, &tmplErr) {
+			fmt.Fprint(os.Stderr, tmplErr.Excerpt())
+		}
+
+		var nfErr *helpers.NotFoundError
+		if errors.As(err, &nfErr) && len(nfErr.Suggestions) > 0 {
+			fmt.Fprintln(os.Stderr, "\nDid you mean one of these?")
+			for _, name := range nfErr.Suggestions {
+				fmt.Fprintf(os.Stderr, "  %s\n", name)
+			}
+		}
+
+		var usageErr *usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprintln(os.Stderr, "Run 'clip --help' for usage.")
+		}
+
+		os.Exit(exitCode(err))
 	}
 }
 
 func init() {
-	// functions that will always be run when this app is called
-	// https://godoc.org/github.com/spf13/cobra#OnInitialize
-	cobra.OnInitialize(initClip)
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &usageError{err: err}
+	})
 
 	// config defaults
 	viper.SetDefault("editor", "nano")
 	viper.SetDefault("vars", map[string]interface{}{"name": "Clip User"})
+	viper.SetDefault("fuzzy", false)
+	viper.SetDefault("system_templatedirs", true)
+	viper.SetDefault("clipboard.backend", clipboard.BackendAuto)
+	viper.SetDefault("history.enabled", true)
+	viper.SetDefault("history.max_entries", 500)
+	viper.SetDefault("history.max_age", "")
+	viper.SetDefault("usage.enabled", true)
 
 	// command Line flags
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.clip.yml)")
-	rootCmd.PersistentFlags().StringVarP(&templateDir, "templatedir", "t", "", "location of template directory (default is $HOME/clip)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $XDG_CONFIG_HOME/clip/config.yml, or $HOME/.clip.yml if it exists)")
+	rootCmd.PersistentFlags().StringVarP(&templateDir, "templatedir", "t", "", "location of template directory, replacing the configured template directories (default is $XDG_DATA_HOME/clip/templates)")
+	rootCmd.PersistentFlags().StringVar(&backend, "backend", "", "clipboard backend to use: "+strings.Join(clipboard.Backends(), ", ")+" (default is auto)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "configuration profile to use (overrides CLIP_PROFILE and the 'profile' config key)")
+	rootCmd.PersistentFlags().VarP(&output, "output", "o", "output format: "+strings.Join(outputFormats, ", "))
+	rootCmd.PersistentFlags().BoolVar(&noLocal, "no-local", false, "don't look for project-local templates and config (.clip/) in the current directory or its parents")
 	rootCmd.Flags().BoolVarP(&showBuild, "version", "v", false, "clip version and build info")
+	addVarFlags(rootCmd.Flags())
+	rootCmd.SetHelpFunc(templateHelpFunc(rootCmd.HelpFunc()))
 
 	// use viper to bind config to CLI flags
 	if err := viper.BindPFlag("templatedir", rootCmd.PersistentFlags().Lookup("templatedir")); err != nil {
 		log.Fatal("Failed to bind `templatedir` flag")
 	}
+	if err := viper.BindPFlag("clipboard.backend", rootCmd.PersistentFlags().Lookup("backend")); err != nil {
+		log.Fatal("Failed to bind `backend` flag")
+	}
+	if err := viper.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile")); err != nil {
+		log.Fatal("Failed to bind `profile` flag")
+	}
+	if err := viper.BindEnv("profile", "CLIP_PROFILE"); err != nil {
+		log.Fatal("Failed to bind `CLIP_PROFILE` environment variable")
+	}
 }
 
 // initClip will set config defaults, read in config file, and initialize clip template directory if it doesn't exist yet
-func initClip() {
-	cfgName := "clip"
+func initClip(cmd *cobra.Command) error {
 	home, err := homedir.Dir()
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return fmt.Errorf("%w: couldn't find home directory: %w", helpers.ErrInvalidConfig, err)
 	}
 
-	if cfgFile != "" {
-		viper.SetConfigFile(cfgFile)
-	} else {
-		// Set config file to home directory + name ".clip.yml"
-		cfgFile = filepath.Join(home, "."+cfgName+".yml")
-		viper.SetConfigType("yaml")
-		viper.SetConfigFile(cfgFile)
-	}
+	// the config file is searched for in the XDG config directory and the
+	// legacy `~/.clip.yml`, see paths.go
+	paths := defaultPaths(home, cfgFile)
+	viper.SetConfigType("yaml")
+	viper.SetConfigFile(paths.ConfigFile)
 
-	if viper.GetString("templatedir") != "" {
-		viper.Set("templatedir", templateDir)
-	} else {
-		viper.Set("templatedir", filepath.Join(home, cfgName))
-	}
+	viper.SetDefault("templatedir", paths.TemplateDir)
+	viper.SetDefault("clipboard.file", paths.ClipboardFile)
+	viper.SetDefault("history.file", paths.HistoryFile)
+	viper.SetDefault("usage.file", paths.UsageFile)
 
 	viper.AutomaticEnv() // read in environment variables that match
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err != nil {
+		// check if config file exists; anything else is a broken config
+		if _, statErr := os.Stat(viper.ConfigFileUsed()); !os.IsNotExist(statErr) {
+			return fmt.Errorf("%w: failed to read config file '%s': %w", helpers.ErrInvalidConfig, viper.ConfigFileUsed(), err)
+		}
+
 		// I should be able to use Viper to create+write the config for me if it
 		// doesn't exist, but the `SafeWriteConfig` function is still broken upstream:
 		// https://github.com/spf13/viper/pull/450/files
+		fmt.Fprintln(os.Stderr, "Clip config file not found; writing config file to: ", viper.ConfigFileUsed())
+		err := writeBaseConfigFile(viper.ConfigFileUsed(), paths.TemplateDir)
+		if err != nil {
+			return fmt.Errorf("call to write Clip configuration file failed: %w", err)
+		}
+	}
+
+	// the selected profile overrides the top-level settings. A broken
+	// profile is only a warning for the commands used to fix it.
+	if err := applyProfile(); err != nil {
+		if !isConfigCommand(cmd) {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
 
-		fmt.Println("Clip config file not found; writing config file to: ", viper.ConfigFileUsed())
-		// check if config file exists
-		if _, err := os.Stat(viper.ConfigFileUsed()); os.IsNotExist(err) {
-			err := helpers.WriteConfigFile(viper.ConfigFileUsed(), viper.AllSettings())
-			if err != nil {
-				fmt.Println("Call to write Clip configuration file failed: ", err)
-				os.Exit(1)
+	templateDirs, err = templateSearchPath()
+	if err != nil {
+		return err
+	}
+	userTemplateDir = templateDirs[0]
+	projectTemplateDir = ""
+
+	// project-local templates and vars take precedence over the user's.
+	// New templates are still created in the user's template directory
+	// unless `create --local` is used.
+	if !noLocal {
+		if cwd, err := os.Getwd(); err == nil {
+			if p, ok := findProject(cwd, home); ok {
+				if err := loadProjectConfig(p); err != nil {
+					return err
+				}
+				projectTemplateDir = filepath.Join(p.Dir, projectDirName, "templates")
+				if p.TemplateDir != "" {
+					templateDirs = append([]string{p.TemplateDir}, templateDirs...)
+				}
 			}
 		}
 	}
 
-	// if template directory doesn't exist, create it
-	if _, err := os.Stat(viper.GetString("templatedir")); os.IsNotExist(err) {
-		err = os.MkdirAll(viper.GetString("templatedir"), 0755)
+	// if the user's template directory doesn't exist, create it. the
+	// others are optional.
+	if _, err := os.Stat(userTemplateDir); os.IsNotExist(err) {
+		err = os.MkdirAll(userTemplateDir, 0755)
 		if err != nil {
-			fmt.Printf("Could not initialize template directory: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Could not initialize template directory: %v\n", err)
 		} else {
-			fmt.Println("Template directory created at: " + viper.GetString("templatedir"))
+			fmt.Fprintln(os.Stderr, "Template directory created at: "+userTemplateDir)
 		}
 	}
+
+	return nil
 }
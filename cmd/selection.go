@@ -0,0 +1,99 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/pflag"
+
+	"github.com/tjhop/clip/helpers"
+)
+
+var (
+	selectTags  []string // --tags
+	selectQuery string   // --query
+)
+
+// addSelectionFlags registers the flags that select templates by their
+// tags, for commands that work on a set of templates
+func addSelectionFlags(flags *pflag.FlagSet) {
+	flags.StringSliceVar(&selectTags, "tags", []string{}, "comma separated list of tags to filter templates (matches any of them)")
+	flags.StringVarP(&selectQuery, "query", "q", "", "tag query to filter templates, ie 'work AND (oncall OR release) AND NOT archived'")
+}
+
+// selectionQuery returns the tag query built from --tags and --query (which
+// must both match if both are given), or nil if neither was given
+func selectionQuery() (*helpers.TagQuery, error) {
+	var query *helpers.TagQuery
+	if len(selectTags) > 0 {
+		query = helpers.AnyTagQuery(selectTags...)
+	}
+
+	if selectQuery != "" {
+		q, err := helpers.ParseTagQuery(selectQuery)
+		if err != nil {
+			return nil, &usageError{err: err}
+		}
+
+		if query != nil {
+			q = query.And(q)
+		}
+		query = q
+	}
+
+	return query, nil
+}
+
+// selectTemplates returns the templates matching the selection flags, or all
+// templates if none were given. Templates that can't be loaded to check
+// their tags are skipped with a warning, unless strict is set, for commands
+// that would rather stop than act on part of the selection.
+func selectTemplates(resolver *helpers.Resolver, strict bool) ([]helpers.Template, error) {
+	query, err := selectionQuery()
+	if err != nil {
+		return nil, err
+	}
+
+	templates, err := resolver.List()
+	if err != nil || query == nil {
+		return templates, err
+	}
+
+	var selected []helpers.Template
+	for _, t := range templates {
+		tmpl, err := helpers.LoadTemplateFile(t.Path)
+		if err != nil {
+			if strict {
+				return nil, fmt.Errorf("couldn't load Clip template '%s' to check for tags: %w", t.Name, err)
+			}
+			fmt.Fprintf(os.Stderr, "Warning: skipping Clip template '%s', couldn't load it to check for tags: %v\n", t.Name, err)
+			continue
+		}
+
+		if query.Match(tmpl.Tags) {
+			selected = append(selected, t)
+		}
+	}
+
+	return selected, nil
+}
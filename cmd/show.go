@@ -23,11 +23,8 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"path/filepath"
-	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 var showCmd = &cobra.Command{
@@ -35,13 +32,12 @@ var showCmd = &cobra.Command{
 	Aliases: []string{"cat", "dump"},
 	Short:   "Show the raw Clip template file",
 	Long:    `Show the output of the raw clip template file (pretty much just cat the file)`,
-	Args:    cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		templateFilename := filepath.Join(viper.GetString("templatedir"), os.Args[len(os.Args)-1]+".yml")
-		err := showClipTemplate(templateFilename)
-		if err != nil {
-			fmt.Printf("Call to show template failed: %v\n", err)
+	Args:    usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := showClipTemplate(args[0]); err != nil {
+			return fmt.Errorf("call to show template failed: %w", err)
 		}
+		return nil
 	},
 }
 
@@ -49,18 +45,36 @@ func init() {
 	rootCmd.AddCommand(showCmd)
 }
 
-func showClipTemplate(filename string) error {
+func showClipTemplate(name string) error {
 	// check if template file exists
-	if _, err := os.Stat(filename); os.IsNotExist(err) {
-		fmt.Printf("Couldn't find a clip template with the name: '%s'\n", strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)))
-	} else {
-		buf, err := os.ReadFile(filename)
-		if err != nil {
-			return fmt.Errorf("failed to read template file: %w", err)
-		}
+	t, err := templateResolver().Find(name)
+	if err != nil {
+		return err
+	}
+
+	buf, err := os.ReadFile(t.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read template file: %w", err)
+	}
 
+	if output == outputText {
 		fmt.Println(string(buf))
+		return nil
 	}
 
-	return nil
+	info, tmpl, err := newTemplateOutput(t, loadUsage())
+	if err != nil {
+		return err
+	}
+
+	vars := tmpl.Template.Vars
+	if vars == nil {
+		vars = map[string]interface{}{}
+	}
+	return printOutput(showOutput{
+		templateOutput: info,
+		Vars:           vars,
+		Text:           tmpl.Template.Text,
+		Content:        string(buf),
+	}, nil)
 }
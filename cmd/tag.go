@@ -0,0 +1,365 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tjhop/clip/helpers"
+)
+
+var tagDryRun bool // --dry-run
+
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "List and change the tags of Clip templates",
+	Long: `List and change the tags of Clip templates. The commands that change tags
+rewrite every affected template file in place, keeping its comments and
+formatting, and print a summary of the templates they changed. Use --dry-run
+to see the summary without changing anything.
+
+Templates are given by name, or selected with --tags/--query (see 'clip list
+--help' for the query syntax). 'rename' and 'merge' change every template
+unless a selection is given, and also move the children of hierarchical
+tags (renaming 'work' to 'job' turns 'work/aws' into 'job/aws'). 'remove'
+only removes the exact tag given.
+
+Example:
+  clip tag list
+  clip tag add standup work/handoff work
+  clip tag add --query 'oncall AND NOT work' work
+  clip tag remove standup archived
+  clip tag rename golang go
+  clip tag merge k8s kube kubernetes --dry-run`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return tagListCmd.RunE(cmd, args)
+	},
+}
+
+var tagListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List all tags used in the templates (same as 'clip list --tags-only')",
+	Args:    usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := listTemplateTags(templateResolver()); err != nil {
+			return fmt.Errorf("call to list Clip template tags failed: %w", err)
+		}
+		return nil
+	},
+}
+
+var tagAddCmd = &cobra.Command{
+	Use:   "add [Clip template...] <tag>",
+	Short: "Add a tag to Clip templates",
+	Args:  usageArgs(cobra.MinimumNArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names, tag := args[:len(args)-1], args[len(args)-1]
+		return retag(names, true, func(tags []string) ([]string, bool) {
+			if slices.Contains(tags, tag) {
+				return tags, false
+			}
+			return append(tags, tag), true
+		}, tag)
+	},
+}
+
+var tagRemoveCmd = &cobra.Command{
+	Use:     "remove [Clip template...] <tag>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a tag from Clip templates",
+	Args:    usageArgs(cobra.MinimumNArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names, tag := args[:len(args)-1], args[len(args)-1]
+		return retag(names, true, func(tags []string) ([]string, bool) {
+			return slices.DeleteFunc(tags, func(t string) bool { return t == tag }), true
+		}, tag)
+	},
+}
+
+var tagRenameCmd = &cobra.Command{
+	Use:   "rename <old> <new>",
+	Short: "Rename a tag in every Clip template that has it",
+	Args:  usageArgs(cobra.ExactArgs(2)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return retag(nil, false, mergeTags(args[:1], args[1]), args...)
+	},
+}
+
+var tagMergeCmd = &cobra.Command{
+	Use:   "merge <tag>... <into>",
+	Short: "Replace several tags with one in every Clip template that has them",
+	Long: `Replace the given tags with the last one in every Clip template that has any of
+them (or their children). The tag keeps the position of the first of them in
+each template.`,
+	Args: usageArgs(cobra.MinimumNArgs(2)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return retag(nil, false, mergeTags(args[:len(args)-1], args[len(args)-1]), args...)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+	tagCmd.AddCommand(tagListCmd, tagAddCmd, tagRemoveCmd, tagRenameCmd, tagMergeCmd)
+
+	// command Line flags
+	addSelectionFlags(tagListCmd.Flags())
+	tagListCmd.Flags().BoolVar(&listTree, "tree", false, "list tags as a tree with template counts")
+	for _, cmd := range []*cobra.Command{tagAddCmd, tagRemoveCmd, tagRenameCmd, tagMergeCmd} {
+		addSelectionFlags(cmd.Flags())
+		cmd.Flags().BoolVar(&tagDryRun, "dry-run", false, "print the templates that would change without changing them")
+	}
+}
+
+// mergeTags returns a retag function that replaces the tags in from with
+// into, at the position of the first of them. Their children are moved
+// under into, so renaming `work` to `job` turns `work/aws` into `job/aws`.
+func mergeTags(from []string, into string) func([]string) ([]string, bool) {
+	return func(tags []string) ([]string, bool) {
+		var merged []string
+		for _, tag := range tags {
+			for _, f := range from {
+				if helpers.IsTagOrChild(tag, f) {
+					tag = into + strings.TrimPrefix(tag, f)
+					break
+				}
+			}
+			if !slices.Contains(merged, tag) {
+				merged = append(merged, tag)
+			}
+		}
+		return merged, true
+	}
+}
+
+// retag applies change to the tags of the templates given by name or by the
+// selection flags (or all templates, if neither is given and
+// selectionRequired is false), and rewrites the files whose tags changed
+func retag(names []string, selectionRequired bool, change func([]string) ([]string, bool), tagArgs ...string) error {
+	for _, tag := range tagArgs {
+		if strings.TrimSpace(tag) == "" {
+			return &usageError{err: fmt.Errorf("tags can't be empty")}
+		}
+	}
+
+	templates, err := tagTemplates(names, selectionRequired)
+	if err != nil {
+		return err
+	}
+
+	// load every template and work out its new tags before writing any of
+	// them, so a broken template doesn't leave the others half updated
+	type update struct {
+		t             helpers.Template
+		doc           *helpers.Document
+		before, after []string
+	}
+	var updates []update
+	for _, t := range templates {
+		doc, err := helpers.LoadDocument(t.Path)
+		if err != nil {
+			return fmt.Errorf("no action taken: couldn't load Clip template file '%s': %w", t.Name, err)
+		}
+
+		before := documentTags(doc)
+		after, ok := change(slices.Clone(before))
+		if !ok || slices.Equal(before, after) {
+			continue
+		}
+
+		if err := setDocumentTags(doc, after); err != nil {
+			return fmt.Errorf("no action taken: failed to update tags of Clip template '%s': %w", t.Name, err)
+		}
+		updates = append(updates, update{t: t, doc: doc, before: before, after: after})
+	}
+
+	var written []string
+	entries := []tagChangeOutput{}
+	for _, u := range updates {
+		if !tagDryRun {
+			if err := u.doc.WriteFile(u.t.Path); err != nil {
+				if len(written) == 0 {
+					return fmt.Errorf("failed to update Clip template file '%s', no templates were changed: %w", u.t.Name, err)
+				}
+				return fmt.Errorf("failed to update Clip template file '%s' after updating %d of %d templates (%s): %w", u.t.Name, len(written), len(updates), strings.Join(written, ", "), err)
+			}
+		}
+
+		written = append(written, u.t.Name)
+		added, removed := tagChanges(u.before, u.after)
+		entries = append(entries, tagChangeOutput{Name: u.t.Name, Path: u.t.Path, Added: added, Removed: removed, DryRun: tagDryRun})
+
+		// the text output shows the progress as files are written
+		if output == outputText {
+			fmt.Printf("%s: %s\n", u.t.Path, tagDiff(u.before, u.after))
+		}
+	}
+
+	return printOutput(entries, func() error {
+		verb := "updated"
+		if tagDryRun {
+			verb = "would be updated (dry run)"
+		}
+		noun := "templates"
+		if len(written) == 1 {
+			noun = "template"
+		}
+		fmt.Printf("%d Clip %s %s\n", len(written), noun, verb)
+		return nil
+	})
+}
+
+// tagTemplates returns the templates named on the command line, followed by
+// the templates matching the selection flags
+func tagTemplates(names []string, selectionRequired bool) ([]helpers.Template, error) {
+	resolver := templateResolver()
+
+	query, err := selectionQuery()
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 && query == nil && selectionRequired {
+		return nil, &usageError{err: fmt.Errorf("no Clip templates given: name them or select them with --tags/--query")}
+	}
+
+	var templates []helpers.Template
+	for _, name := range names {
+		t, err := resolver.ResolveWritable(name)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+
+	if query != nil || len(names) == 0 {
+		// the tags of every selected template are rewritten, so a broken
+		// one stops the command instead of being left out
+		selected, err := selectTemplates(resolver, true)
+		if err != nil {
+			return nil, err
+		}
+
+		readOnly := 0
+		for _, t := range selected {
+			if resolver.IsReadOnly(t) {
+				readOnly++
+				continue
+			}
+			if !slices.ContainsFunc(templates, func(other helpers.Template) bool { return other.Path == t.Path }) {
+				templates = append(templates, t)
+			}
+		}
+		if readOnly > 0 {
+			fmt.Fprintf(os.Stderr, "Note: skipping %d read-only Clip templates in the system template directories\n", readOnly)
+		}
+	}
+
+	return templates, nil
+}
+
+// documentTags returns the tags of a template document
+func documentTags(doc *helpers.Document) []string {
+	var tags []string
+	if node := doc.Lookup("tags"); node != nil && node.Kind == yaml.SequenceNode {
+		for _, item := range node.Content {
+			tags = append(tags, item.Value)
+		}
+	}
+
+	return tags
+}
+
+// setDocumentTags changes the tags of a template document to tags. Items
+// that are kept keep their comments, and new tags take the place of the
+// items that are dropped (so a renamed tag stays where it was) before any
+// are appended.
+func setDocumentTags(doc *helpers.Document, tags []string) error {
+	kept := make(map[string]bool)
+	var dropped []*yaml.Node
+	if node := doc.Lookup("tags"); node != nil && node.Kind == yaml.SequenceNode {
+		for _, item := range node.Content {
+			if slices.Contains(tags, item.Value) && !kept[item.Value] {
+				kept[item.Value] = true
+			} else {
+				dropped = append(dropped, item)
+			}
+		}
+	}
+
+	var added []interface{}
+	for _, tag := range tags {
+		if kept[tag] {
+			continue
+		}
+		if len(dropped) > 0 {
+			dropped[0].Value, dropped[0].Tag, dropped[0].Style = tag, "!!str", 0
+			dropped = dropped[1:]
+		} else {
+			added = append(added, tag)
+		}
+		kept[tag] = true
+	}
+
+	doc.RemoveItems("tags", func(item *yaml.Node) bool { return slices.Contains(dropped, item) })
+	if len(added) > 0 {
+		return doc.Append("tags", added...)
+	}
+
+	return nil
+}
+
+// tagDiff describes a change of tags, ie `+work -archived`
+func tagDiff(before, after []string) string {
+	var changes []string
+	added, removed := tagChanges(before, after)
+	for _, tag := range added {
+		changes = append(changes, "+"+tag)
+	}
+	for _, tag := range removed {
+		changes = append(changes, "-"+tag)
+	}
+
+	return strings.Join(changes, " ")
+}
+
+// tagChanges returns the tags in after that aren't in before, and the tags
+// in before that aren't in after
+func tagChanges(before, after []string) ([]string, []string) {
+	added, removed := []string{}, []string{}
+	for _, tag := range after {
+		if !slices.Contains(before, tag) {
+			added = append(added, tag)
+		}
+	}
+	for _, tag := range before {
+		if !slices.Contains(after, tag) {
+			removed = append(removed, tag)
+		}
+	}
+
+	return added, removed
+}
@@ -0,0 +1,241 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/tjhop/clip/helpers"
+)
+
+// templateFlagSet builds a flag for every variable declared in the schema of
+// a template, so `clip copy standup --project foo` sets the `project` var,
+// and for the other variables set in the template's `vars`. Variables whose
+// names clash with an existing flag of cmd are skipped; those can still be
+// set with `--set`.
+func templateFlagSet(cmd *cobra.Command, tmpl helpers.TemplateFile) *pflag.FlagSet {
+	fs := pflag.NewFlagSet("template variables", pflag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+
+	for _, name := range tmpl.Template.Schema.Names() {
+		if !isTemplateFlagName(cmd, name) {
+			continue
+		}
+
+		spec := tmpl.Template.Schema[name]
+		usage := spec.Description
+		if len(spec.Enum) > 0 {
+			usage = strings.TrimSpace(fmt.Sprintf("%s (one of: %s)", usage, strings.Join(spec.Enum, ", ")))
+		}
+		if spec.Required && spec.Default == nil {
+			usage = strings.TrimSpace(usage + " (required)")
+		}
+
+		def := ""
+		if spec.Default != nil {
+			def = fmt.Sprint(spec.Default)
+		}
+
+		switch spec.Type {
+		case helpers.VarTypeBool:
+			fs.Bool(name, def == "true", usage)
+		case helpers.VarTypeInt:
+			fs.Int(name, 0, usage)
+		case helpers.VarTypeFloat:
+			fs.Float64(name, 0, usage)
+		case helpers.VarTypeList:
+			fs.StringSlice(name, nil, usage)
+		default:
+			fs.String(name, def, usage)
+		}
+
+		// show the declared default rather than the zero value of the flag
+		if def != "" {
+			fs.Lookup(name).DefValue = def
+		}
+	}
+
+	// undeclared vars get a flag typed after their value in the template.
+	// Maps can only be set with --set.
+	names := make([]string, 0, len(tmpl.Template.Vars))
+	for name := range tmpl.Template.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, declared := tmpl.Template.Schema[name]; declared || !isTemplateFlagName(cmd, name) {
+			continue
+		}
+
+		switch value := tmpl.Template.Vars[name].(type) {
+		case map[string]interface{}:
+			continue
+		case bool:
+			fs.Bool(name, value, "")
+		case []interface{}:
+			def := make([]string, 0, len(value))
+			for _, item := range value {
+				def = append(def, fmt.Sprint(item))
+			}
+			fs.StringSlice(name, def, "")
+		case nil:
+			fs.String(name, "", "")
+		default:
+			fs.String(name, fmt.Sprint(value), "")
+		}
+	}
+
+	return fs
+}
+
+func isTemplateFlagName(cmd *cobra.Command, name string) bool {
+	return name != "" && cmd.Flags().Lookup(name) == nil && !strings.ContainsAny(name, " =")
+}
+
+// checkFlagsBeforeTemplate checks the flags given before the template name in
+// args. The per-template flags aren't known until the template is loaded, so
+// they have to come after its name; any other unknown flag before it is an
+// error (otherwise `--typo name` would take the name as its value).
+func checkFlagsBeforeTemplate(cmd *cobra.Command, args []string) error {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return nil
+		case strings.HasPrefix(arg, "--"):
+			name, _, hasValue := strings.Cut(arg[2:], "=")
+			f := cmd.Flags().Lookup(name)
+			if f == nil {
+				return &usageError{err: fmt.Errorf("unknown flag: --%s (template variable flags go after the template name)", name)}
+			}
+			if !hasValue && f.NoOptDefVal == "" {
+				i++
+			}
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			shorthands := arg[1:]
+			for j := 0; j < len(shorthands); j++ {
+				f := cmd.Flags().ShorthandLookup(shorthands[j : j+1])
+				if f == nil {
+					return &usageError{err: fmt.Errorf("unknown shorthand flag: '%c' in %s", shorthands[j], arg)}
+				}
+				if f.NoOptDefVal == "" {
+					// the value is the rest of the argument, or the next one
+					if j == len(shorthands)-1 {
+						i++
+					}
+					break
+				}
+			}
+		default:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// commandArgs returns the flags and arguments given to cmd on the command
+// line, without the names of the commands
+func commandArgs(cmd *cobra.Command) ([]string, error) {
+	_, args, err := cmd.Root().Find(os.Args[1:])
+	if err != nil {
+		return nil, &usageError{err: err}
+	}
+
+	return args, nil
+}
+
+// discardValue stands in for the flags of a command when its arguments are
+// parsed again with the per-template flags, so they aren't set twice
+type discardValue struct {
+	typ string
+}
+
+func (v discardValue) String() string   { return "" }
+func (v discardValue) Set(string) error { return nil }
+func (v discardValue) Type() string     { return v.typ }
+
+// templateFlagVars parses the per-template flags out of args and returns the
+// variables that were set. The flags of cmd (which cobra has already parsed)
+// are accepted and ignored, and anything else is a usage error.
+func templateFlagVars(cmd *cobra.Command, tmpl helpers.TemplateFile, args []string) (map[string]interface{}, error) {
+	fs := templateFlagSet(cmd, tmpl)
+	parser := pflag.NewFlagSet(cmd.Name(), pflag.ContinueOnError)
+	parser.SetOutput(io.Discard)
+	parser.Usage = func() {}
+	parser.AddFlagSet(fs)
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		parser.AddFlag(&pflag.Flag{
+			Name:        f.Name,
+			Shorthand:   f.Shorthand,
+			NoOptDefVal: f.NoOptDefVal,
+			Value:       discardValue{typ: f.Value.Type()},
+		})
+	})
+
+	if err := parser.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return nil, &usageError{err: err}
+		}
+		return nil, &usageError{err: fmt.Errorf("%w (not a variable of template '%s', see 'clip copy %s --help')", err, cmd.Flags().Arg(0), cmd.Flags().Arg(0))}
+	}
+	if parser.NArg() != 1 {
+		return nil, &usageError{err: fmt.Errorf("accepts 1 Clip template, received %d arguments: %s", parser.NArg(), strings.Join(parser.Args(), " "))}
+	}
+
+	vars := make(map[string]interface{})
+	parser.Visit(func(f *pflag.Flag) {
+		if fs.Lookup(f.Name) == nil {
+			return
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			vars[f.Name] = sv.GetSlice()
+		} else {
+			vars[f.Name] = f.Value.String()
+		}
+	})
+
+	return vars, nil
+}
+
+// printTemplateHelp prints the description and variables of a template for
+// `clip copy <template> --help`
+func printTemplateHelp(w io.Writer, cmd *cobra.Command, name string, tmpl helpers.TemplateFile) {
+	fmt.Fprintf(w, "Clip template: %s\n", name)
+	if tmpl.Description != "" {
+		fmt.Fprintf(w, "\n%s\n", strings.TrimSpace(tmpl.Description))
+	}
+
+	if fs := templateFlagSet(cmd, tmpl); fs.HasFlags() {
+		fmt.Fprintf(w, "\nTemplate Variables:\n%s", fs.FlagUsages())
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,135 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+
+	"github.com/tjhop/clip/helpers"
+)
+
+// template directories, set by initClip
+var (
+	// templateDirs is the template search path
+	templateDirs []string
+
+	// userTemplateDir is where new templates are created, the first of the
+	// configured template directories
+	userTemplateDir string
+
+	// projectTemplateDir is the `.clip/templates` directory of the project
+	// clip is running in, if any, where `create --local` creates templates.
+	// It may not exist yet.
+	projectTemplateDir string
+)
+
+// templateResolver returns the resolver for the template search path.
+// Names can be namespaced with slashes, ie `work/oncall/handoff` lives in
+// `$templatedir/work/oncall/handoff.yml`.
+//
+// Commands that only read templates (copy, show) look them up with Find,
+// which does prefix/fuzzy matching if `fuzzy` is enabled in the config.
+// Commands that change templates always use the exact name with
+// ResolveWritable, which skips the read-only system template directories.
+func templateResolver() *helpers.Resolver {
+	r := helpers.NewResolver(templateDirs...)
+	r.Fuzzy = viper.GetBool("fuzzy")
+	r.ReadOnly = systemTemplateDirs()
+	r.NewRoot = userTemplateDir
+
+	return r
+}
+
+// templateSearchPath returns the template directories in order of
+// precedence: the `--templatedir` flag if given, otherwise the
+// `templatedirs` list (or the single `templatedir`) from the config file,
+// followed by the system template directories unless
+// `system_templatedirs` is disabled
+func templateSearchPath() ([]string, error) {
+	var dirs []string
+	switch {
+	case templateDir != "":
+		dirs = []string{templateDir}
+	case viper.IsSet("templatedirs"):
+		dirs = viper.GetStringSlice("templatedirs")
+	default:
+		dirs = []string{viper.GetString("templatedir")}
+	}
+
+	if viper.GetBool("system_templatedirs") {
+		dirs = append(dirs, systemTemplateDirs()...)
+	}
+
+	var searchPath []string
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+
+		expanded, err := homedir.Expand(dir)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid template directory '%s': %w", helpers.ErrInvalidConfig, dir, err)
+		}
+
+		dir = filepath.Clean(expanded)
+		if !seen[dir] {
+			seen[dir] = true
+			searchPath = append(searchPath, dir)
+		}
+	}
+
+	if len(searchPath) == 0 {
+		return nil, fmt.Errorf("%w: no template directories configured", helpers.ErrInvalidConfig)
+	}
+
+	return searchPath, nil
+}
+
+// systemTemplateDirs returns the shared template directories, which are
+// `/etc/clip/templates` followed by `clip/templates` in each of
+// $XDG_DATA_DIRS
+func systemTemplateDirs() []string {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	dirs := []string{"/etc/clip/templates"}
+
+	dataDirs := os.Getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+	for _, dir := range filepath.SplitList(dataDirs) {
+		// relative paths are invalid per the XDG base directory spec
+		if filepath.IsAbs(dir) {
+			dirs = append(dirs, filepath.Join(dir, "clip", "templates"))
+		}
+	}
+
+	return dirs
+}
@@ -0,0 +1,74 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+
+	"github.com/tjhop/clip/usage"
+)
+
+// getUsage returns the template usage store configured by the `usage.*`
+// config keys
+func getUsage() *usage.Store {
+	return usage.NewStore(viper.GetString("usage.file"))
+}
+
+// recordUsage records a use of a template if usage tracking is enabled.
+// Like the clipboard history, failing to record it never fails the command.
+func recordUsage(name string) {
+	if !viper.GetBool("usage.enabled") {
+		return
+	}
+
+	if err := getUsage().Record(name); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to record template usage: %v\n", err)
+	}
+}
+
+// loadUsage returns the usage of every template. An unreadable usage file
+// is reported, and treated as no template having been used.
+func loadUsage() map[string]usage.Stats {
+	stats, err := getUsage().Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load template usage: %v\n", err)
+		return map[string]usage.Stats{}
+	}
+
+	return stats
+}
+
+// renameUsage moves the usage of a renamed template to its new name
+func renameUsage(oldName, newName string) {
+	if err := getUsage().Rename(oldName, newName); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to update template usage: %v\n", err)
+	}
+}
+
+// removeUsage forgets the usage of a removed template
+func removeUsage(name string) {
+	if err := getUsage().Remove(name); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to update template usage: %v\n", err)
+	}
+}
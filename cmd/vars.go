@@ -0,0 +1,124 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tjhop/clip/helpers"
+)
+
+// envVarPrefix is the prefix of environment variables that set template
+// variables, ie `CLIP_VAR_PROJECT=clip` sets the `project` variable
+const envVarPrefix = "CLIP_VAR_"
+
+var (
+	setVars     []string // --set key=value
+	setFileVars []string // --set-file key=path
+	valuesFiles []string // --values file.yml
+)
+
+// addVarFlags registers the flags used to set template variables at copy
+// time. They're added to both `copy` and the root command, since running
+// `clip $template` copies the template too.
+func addVarFlags(flags *pflag.FlagSet) {
+	flags.StringArrayVar(&setVars, "set", []string{}, "set a template variable (key=value, use dots for nested keys, can be repeated)")
+	flags.StringArrayVar(&setFileVars, "set-file", []string{}, "set a template variable to the contents of a file (key=path, can be repeated)")
+	flags.StringArrayVarP(&valuesFiles, "values", "f", []string{}, "YAML/JSON file of template variables (can be repeated)")
+}
+
+// varOverlays returns the runtime template variables in order of increasing
+// precedence:
+//
+//	--values files (in the order given)
+//	CLIP_VAR_<NAME> environment variables
+//	--set-file flags
+//	per-template --<var> flags (templateVars)
+//	--set flags
+//
+// These all take precedence over the vars in the config file and template.
+func varOverlays(templateVars map[string]interface{}) ([]map[string]interface{}, error) {
+	var overlays []map[string]interface{}
+
+	for _, filename := range valuesFiles {
+		buf, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read values file: %w", err)
+		}
+
+		values := make(map[string]interface{})
+		if err := yaml.Unmarshal(buf, &values); err != nil {
+			return nil, fmt.Errorf("failed to parse values file '%s': %w", filename, err)
+		}
+		overlays = append(overlays, values)
+	}
+
+	env := make(map[string]interface{})
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		if name, ok := strings.CutPrefix(key, envVarPrefix); ok && name != "" {
+			env[strings.ToLower(name)] = value
+		}
+	}
+	overlays = append(overlays, env)
+
+	files := make(map[string]interface{})
+	for _, kv := range setFileVars {
+		key, filename, err := splitVarAssignment("--set-file", kv)
+		if err != nil {
+			return nil, err
+		}
+
+		buf, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file for variable '%s': %w", key, err)
+		}
+		helpers.SetVarPath(files, key, string(buf))
+	}
+	overlays = append(overlays, files, templateVars)
+
+	sets := make(map[string]interface{})
+	for _, kv := range setVars {
+		key, value, err := splitVarAssignment("--set", kv)
+		if err != nil {
+			return nil, err
+		}
+		helpers.SetVarPath(sets, key, value)
+	}
+	overlays = append(overlays, sets)
+
+	return overlays, nil
+}
+
+func splitVarAssignment(flag, kv string) (string, string, error) {
+	key, value, ok := strings.Cut(kv, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return "", "", &usageError{err: fmt.Errorf("invalid %s value '%s': expected key=value", flag, kv)}
+	}
+
+	return key, value, nil
+}
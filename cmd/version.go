@@ -37,8 +37,11 @@ var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print Clip build info",
 	Long:  `Print Clip build info`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("clip %s (%s) [built %s]\n", version, commit, builddate)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return printOutput(versionOutput{Version: version, Commit: commit, BuildDate: builddate}, func() error {
+			fmt.Printf("clip %s (%s) [built %s]\n", version, commit, builddate)
+			return nil
+		})
 	},
 }
 
@@ -0,0 +1,122 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/tjhop/clip/clipboard"
+)
+
+var (
+	watchInterval time.Duration
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Record external clipboard changes in the clipboard history",
+	Long: `Watch the clipboard and record every change in the clipboard history, including
+content copied outside of Clip. Runs until interrupted (Ctrl-C/SIGTERM).
+
+Every command in the 'watch.hooks' config list is run through the shell after a
+change is recorded, with the new clipboard contents on stdin and the
+'CLIP_SOURCE' environment variable set to 'watch'.
+
+Example:
+  clip watch
+  clip watch --interval 2s`,
+	Args: usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cb, err := getClipboard()
+		if err != nil {
+			return fmt.Errorf("call to watch clipboard failed: failed to open clipboard: %w", err)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if err := watchClipboard(ctx, cb, viper.GetDuration("watch.interval")); err != nil {
+			return fmt.Errorf("call to watch clipboard failed: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	// command Line flags
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", clipboard.DefaultInterval, "how often to check the clipboard for changes")
+
+	// use viper to bind config to CLI flags
+	if err := viper.BindPFlag("watch.interval", watchCmd.Flags().Lookup("interval")); err != nil {
+		log.Fatal("Failed to bind `interval` flag")
+	}
+}
+
+// watchClipboard records clipboard changes to history and runs the
+// configured hooks until ctx is done
+func watchClipboard(ctx context.Context, cb clipboard.Clipboard, interval time.Duration) error {
+	w := &clipboard.Watcher{
+		Clipboard: cb,
+		Interval:  interval,
+		OnChange: func(text string) {
+			recordHistory("watch", text)
+			runWatchHooks(ctx, viper.GetStringSlice("watch.hooks"), text)
+		},
+		OnError: func(err error) {
+			fmt.Fprintf(os.Stderr, "Failed to read clipboard: %v\n", err)
+		},
+	}
+
+	return w.Run(ctx)
+}
+
+func runWatchHooks(ctx context.Context, hooks []string, text string) {
+	for _, hook := range hooks {
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.CommandContext(ctx, "cmd", "/C", hook)
+		} else {
+			cmd = exec.CommandContext(ctx, "sh", "-c", hook)
+		}
+		cmd.Stdin = strings.NewReader(text)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		cmd.Env = append(os.Environ(), "CLIP_SOURCE=watch")
+
+		if err := cmd.Run(); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "Clipboard watch hook '%s' failed: %v\n", hook, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tjhop/clip/helpers"
+)
+
+var whichCmd = &cobra.Command{
+	Use:   "which <Clip template>",
+	Short: "Show which file a Clip template resolves to",
+	Long: `Print the file a Clip template name resolves to, followed by any templates
+with the same name in later template directories that it shadows. Templates
+in the system template directories are marked read-only; the commands that
+change templates (edit, rename, rm, tag) leave them alone.
+
+Example:
+  clip which standup`,
+	Args: usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := whichClipTemplate(args[0]); err != nil {
+			return fmt.Errorf("call to find Clip template failed: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(whichCmd)
+}
+
+func whichClipTemplate(name string) error {
+	resolver := templateResolver()
+	t, err := resolver.Find(name)
+	if err != nil {
+		return err
+	}
+
+	// an error means the name is ambiguous in a later template directory;
+	// the template that was found is still the one that's used
+	var others []helpers.Template
+	shadowed := []string{}
+	if found, err := resolver.Lookup(t.Name); err == nil {
+		for _, other := range found {
+			if other.Root != t.Root {
+				others = append(others, other)
+				shadowed = append(shadowed, other.Path)
+			}
+		}
+	}
+
+	if output == outputText {
+		if resolver.IsReadOnly(t) {
+			fmt.Printf("%s (read-only)\n", t.Path)
+		} else {
+			fmt.Println(t.Path)
+		}
+		for _, other := range others {
+			if resolver.IsReadOnly(other) {
+				fmt.Printf("%s (shadowed, read-only)\n", other.Path)
+			} else {
+				fmt.Printf("%s (shadowed)\n", other.Path)
+			}
+		}
+		return nil
+	}
+
+	info, _, err := newTemplateOutput(t, loadUsage())
+	if err != nil {
+		return err
+	}
+	return printOutput(whichOutput{templateOutput: info, ReadOnly: resolver.IsReadOnly(t), Shadowed: shadowed}, nil)
+}
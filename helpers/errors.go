@@ -0,0 +1,143 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Errors that Clip commands can fail with. They're wrapped with more
+// context, so check for them with errors.Is.
+var (
+	ErrNotFound      = errors.New("Clip template not found")
+	ErrAlreadyExists = errors.New("Clip template already exists")
+	ErrRenderFailed  = errors.New("failed to render Clip template")
+	ErrInvalidConfig = errors.New("invalid Clip configuration")
+)
+
+// templateErrorRegex matches the position prefix text/template puts on parse
+// (`template: NAME:LINE: msg`) and execution (`template: NAME:LINE:COL: msg`)
+// errors
+var templateErrorRegex = regexp.MustCompile(`(?s)^template: [^:]*:(\d+):(?:(\d+):)? (.*)$`)
+
+// TemplateError is a parse or execution error in the text of a template,
+// with the position translated from the template text to the YAML file
+type TemplateError struct {
+	Filename string
+
+	// Phase is either "parse" or "execution"
+	Phase string
+
+	// Line and Column are 1-based positions in the YAML file. Column is 0
+	// if text/template didn't report one (parse errors).
+	Line   int
+	Column int
+
+	Message string
+
+	// Err is the original error from text/template
+	Err error
+
+	source []string
+}
+
+func (e *TemplateError) Error() string {
+	pos := e.Filename
+	if e.Line > 0 {
+		pos = fmt.Sprintf("%s:%d", pos, e.Line)
+		if e.Column > 0 {
+			pos = fmt.Sprintf("%s:%d", pos, e.Column)
+		}
+	}
+
+	return fmt.Sprintf("%s: template %s error: %s", pos, e.Phase, e.Message)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// Excerpt returns the lines of the YAML file around the error, with a caret
+// pointing at the column of the error if it's known
+func (e *TemplateError) Excerpt() string {
+	if e.Line < 1 || e.Line > len(e.source) {
+		return ""
+	}
+
+	var b strings.Builder
+	width := len(strconv.Itoa(e.Line))
+	for n := max(1, e.Line-2); n <= e.Line; n++ {
+		fmt.Fprintf(&b, "%*d | %s\n", width, n, e.source[n-1])
+	}
+
+	if e.Column > 0 {
+		// keep tabs in the source line so the caret lines up with it
+		prefix := []rune(e.source[e.Line-1])
+		if e.Column-1 < len(prefix) {
+			prefix = prefix[:e.Column-1]
+		}
+		indent := strings.Map(func(r rune) rune {
+			if r == '\t' {
+				return r
+			}
+			return ' '
+		}, string(prefix))
+		fmt.Fprintf(&b, "%*s | %s^\n", width, "", indent)
+	}
+
+	return b.String()
+}
+
+// templateError translates an error from text/template into a *TemplateError
+// positioned in the YAML file of tmpl
+func (tmpl TemplateFile) templateError(phase string, err error) *TemplateError {
+	te := &TemplateError{
+		Filename: tmpl.Filename,
+		Phase:    phase,
+		Message:  err.Error(),
+		Err:      err,
+		source:   strings.Split(string(tmpl.source), "\n"),
+	}
+
+	m := templateErrorRegex.FindStringSubmatch(err.Error())
+	if m == nil {
+		return te
+	}
+
+	te.Message = m[3]
+	line, _ := strconv.Atoi(m[1])
+
+	// text/template columns are 0-based byte offsets into the line of the
+	// template text, which is indented (and maybe folded) inside the YAML
+	// file
+	if m[2] == "" {
+		te.Line, _ = tmpl.textPosition(line, 0)
+	} else {
+		col, _ := strconv.Atoi(m[2])
+		te.Line, te.Column = tmpl.textPosition(line, col)
+	}
+
+	return te
+}
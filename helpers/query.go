@@ -0,0 +1,345 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidQuery is returned for tag queries that can't be parsed
+var ErrInvalidQuery = errors.New("invalid tag query")
+
+// TagQuery selects templates by their tags. Queries are made of terms
+// combined with AND, OR, NOT and parentheses, ie:
+//
+//	work AND (oncall OR release) AND NOT archived
+//
+// Operators are case insensitive, and terms next to each other without an
+// operator are ANDed. A term matches a template if any of its tags (or their
+// parent tags, see ParentTags) matches:
+//
+//	work      exactly, so `work` matches `work` and `work/aws`
+//	go*       as a glob (*, ? and [...], see path.Match)
+//	/^go/     as a regular expression, anywhere in the tag unless anchored
+//	"on call" exactly, for tags with spaces or named like an operator
+type TagQuery struct {
+	source string
+	expr   queryExpr
+}
+
+// ParseTagQuery parses a tag query
+func ParseTagQuery(query string) (*TagQuery, error) {
+	tokens, err := lexTagQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%w: empty query", ErrInvalidQuery)
+	}
+
+	p := &queryParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, p.unexpected()
+	}
+
+	return &TagQuery{source: query, expr: expr}, nil
+}
+
+// AnyTagQuery returns a query matching templates with any of the given tags
+// (or their children) exactly, like `clip list --tags a,b`
+func AnyTagQuery(tags ...string) *TagQuery {
+	return &TagQuery{source: strings.Join(tags, " OR "), expr: anyTerm(tags)}
+}
+
+// And returns a query matching the templates that match both q and other
+func (q *TagQuery) And(other *TagQuery) *TagQuery {
+	return &TagQuery{
+		source: fmt.Sprintf("(%s) AND (%s)", q.source, other.source),
+		expr:   andExpr{q.expr, other.expr},
+	}
+}
+
+// Match reports whether a template with the given tags matches the query
+func (q *TagQuery) Match(tags []string) bool {
+	return q.expr.match(tags)
+}
+
+func (q *TagQuery) String() string {
+	return q.source
+}
+
+type queryExpr interface {
+	match(tags []string) bool
+}
+
+type andExpr struct{ left, right queryExpr }
+
+func (e andExpr) match(tags []string) bool { return e.left.match(tags) && e.right.match(tags) }
+
+type orExpr struct{ left, right queryExpr }
+
+func (e orExpr) match(tags []string) bool { return e.left.match(tags) || e.right.match(tags) }
+
+type notExpr struct{ expr queryExpr }
+
+func (e notExpr) match(tags []string) bool { return !e.expr.match(tags) }
+
+type exactTerm string
+
+func (t exactTerm) match(tags []string) bool {
+	return slices.ContainsFunc(tags, func(tag string) bool { return IsTagOrChild(tag, string(t)) })
+}
+
+type anyTerm []string
+
+func (t anyTerm) match(tags []string) bool {
+	return slices.ContainsFunc(tags, func(tag string) bool {
+		return slices.ContainsFunc(t, func(parent string) bool { return IsTagOrChild(tag, parent) })
+	})
+}
+
+type globTerm string
+
+func (t globTerm) match(tags []string) bool {
+	return matchTags(tags, func(tag string) bool {
+		ok, _ := path.Match(string(t), tag)
+		return ok
+	})
+}
+
+type regexTerm struct{ re *regexp.Regexp }
+
+func (t regexTerm) match(tags []string) bool { return matchTags(tags, t.re.MatchString) }
+
+type queryTokenKind int
+
+const (
+	tokenTerm queryTokenKind = iota
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+type queryToken struct {
+	kind queryTokenKind
+	text string
+	term queryExpr
+	pos  int // 1-based position in the query, for errors
+}
+
+// lexTagQuery splits a tag query into operators, parentheses and terms
+func lexTagQuery(query string) ([]queryToken, error) {
+	var tokens []queryToken
+
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, queryToken{kind: tokenOpen, text: "(", pos: pos})
+			i++
+		case r == ')':
+			tokens = append(tokens, queryToken{kind: tokenClose, text: ")", pos: pos})
+			i++
+		case r == '"':
+			end := slices.Index(runes[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated quote at position %d", ErrInvalidQuery, pos)
+			}
+			text := string(runes[i+1 : i+1+end])
+			tokens = append(tokens, queryToken{kind: tokenTerm, text: text, term: exactTerm(text), pos: pos})
+			i += end + 2
+		case r == '/':
+			// the regex ends at the next unescaped slash
+			end := -1
+			for j := i + 1; j < len(runes); j++ {
+				if runes[j] == '\\' {
+					j++
+				} else if runes[j] == '/' {
+					end = j
+					break
+				}
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated regular expression at position %d", ErrInvalidQuery, pos)
+			}
+			re, err := regexp.Compile(string(runes[i+1 : end]))
+			if err != nil {
+				return nil, fmt.Errorf("%w: bad regular expression at position %d: %w", ErrInvalidQuery, pos, err)
+			}
+			text := string(runes[i : end+1])
+			tokens = append(tokens, queryToken{kind: tokenTerm, text: text, term: regexTerm{re}, pos: pos})
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune(`()"`, runes[end]) {
+				end++
+			}
+			text := string(runes[i:end])
+			i = end
+
+			switch strings.ToUpper(text) {
+			case "AND":
+				tokens = append(tokens, queryToken{kind: tokenAnd, text: text, pos: pos})
+				continue
+			case "OR":
+				tokens = append(tokens, queryToken{kind: tokenOr, text: text, pos: pos})
+				continue
+			case "NOT":
+				tokens = append(tokens, queryToken{kind: tokenNot, text: text, pos: pos})
+				continue
+			}
+
+			var term queryExpr = exactTerm(text)
+			if strings.ContainsAny(text, "*?[") {
+				if _, err := path.Match(text, ""); err != nil {
+					return nil, fmt.Errorf("%w: bad glob '%s' at position %d: %w", ErrInvalidQuery, text, pos, err)
+				}
+				term = globTerm(text)
+			}
+			tokens = append(tokens, queryToken{kind: tokenTerm, text: text, term: term, pos: pos})
+		}
+	}
+
+	return tokens, nil
+}
+
+// queryParser is a recursive descent parser for tag queries. From lowest to
+// highest precedence: OR, AND (explicit or implied), NOT.
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() (queryToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return queryToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *queryParser) unexpected() error {
+	if tok, ok := p.peek(); ok {
+		return fmt.Errorf("%w: unexpected '%s' at position %d", ErrInvalidQuery, tok.text, tok.pos)
+	}
+	if p.pos > 0 {
+		last := p.tokens[p.pos-1]
+		return fmt.Errorf("%w: unexpected end of query after '%s' at position %d", ErrInvalidQuery, last.text, last.pos)
+	}
+	return fmt.Errorf("%w: unexpected end of query", ErrInvalidQuery)
+}
+
+func (p *queryParser) parseOr() (queryExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind != tokenOr {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+}
+
+func (p *queryParser) parseAnd() (queryExpr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind == tokenOr || tok.kind == tokenClose {
+			return left, nil
+		}
+		if tok.kind == tokenAnd {
+			p.pos++
+		}
+
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+}
+
+func (p *queryParser) parseNot() (queryExpr, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return nil, p.unexpected()
+	}
+
+	switch tok.kind {
+	case tokenNot:
+		p.pos++
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr}, nil
+	case tokenOpen:
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		next, ok := p.peek()
+		if !ok {
+			return nil, fmt.Errorf("%w: missing ')' for '(' at position %d", ErrInvalidQuery, tok.pos)
+		}
+		if next.kind != tokenClose {
+			return nil, p.unexpected()
+		}
+		p.pos++
+		return expr, nil
+	case tokenTerm:
+		p.pos++
+		return tok.term, nil
+	default:
+		return nil, p.unexpected()
+	}
+}
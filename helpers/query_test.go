@@ -0,0 +1,199 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package helpers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTagQueryMatch(t *testing.T) {
+	tests := []struct {
+		query string
+		match [][]string
+		skip  [][]string
+	}{
+		// NOT binds tighter than AND, which binds tighter than OR
+		{
+			query: "a OR b AND c",
+			match: [][]string{{"a"}, {"b", "c"}},
+			skip:  [][]string{{"b"}, {"c"}, {}},
+		},
+		{
+			query: "(a OR b) AND c",
+			match: [][]string{{"a", "c"}, {"b", "c"}},
+			skip:  [][]string{{"a"}, {"b"}},
+		},
+		{
+			query: "NOT a AND b",
+			match: [][]string{{"b"}},
+			skip:  [][]string{{"a", "b"}, {"a"}},
+		},
+		{
+			query: "NOT (a AND b)",
+			match: [][]string{{"a"}, {"b"}, {}},
+			skip:  [][]string{{"a", "b"}},
+		},
+		{
+			query: "NOT NOT a",
+			match: [][]string{{"a"}},
+			skip:  [][]string{{"b"}},
+		},
+		// terms next to each other are ANDed, and operators are case
+		// insensitive
+		{
+			query: "a b or c",
+			match: [][]string{{"a", "b"}, {"c"}},
+			skip:  [][]string{{"a"}, {"b"}},
+		},
+		{
+			query: "a and not b",
+			match: [][]string{{"a"}},
+			skip:  [][]string{{"a", "b"}},
+		},
+		// quoted terms match exactly, including spaces and operator names
+		{
+			query: `"on call" OR "AND"`,
+			match: [][]string{{"on call"}, {"AND"}},
+			skip:  [][]string{{"on"}, {"call"}, {"and"}},
+		},
+		// globs
+		{
+			query: "go*",
+			match: [][]string{{"go"}, {"golang"}},
+			skip:  [][]string{{"lang-go"}},
+		},
+		{
+			query: "lang-? OR [xy]",
+			match: [][]string{{"lang-c"}, {"x"}},
+			skip:  [][]string{{"lang-go"}, {"z"}},
+		},
+		// regular expressions, with an escaped slash
+		{
+			query: "/^go/",
+			match: [][]string{{"golang"}},
+			skip:  [][]string{{"lang-go"}},
+		},
+		{
+			query: `/^ci\/cd$/`,
+			match: [][]string{{"ci/cd"}},
+			skip:  [][]string{{"ci"}, {"cicd"}},
+		},
+		// parents match their children, and globs and regular
+		// expressions are matched against parents too
+		{
+			query: "work",
+			match: [][]string{{"work"}, {"work/aws"}, {"work/aws/prod"}},
+			skip:  [][]string{{"workshop"}, {"team/work"}},
+		},
+		{
+			query: "work/aws",
+			match: [][]string{{"work/aws/prod"}},
+			skip:  [][]string{{"work"}, {"work/k8s"}},
+		},
+		{
+			query: "work/*",
+			match: [][]string{{"work/aws"}, {"work/aws/prod"}},
+			skip:  [][]string{{"work"}},
+		},
+		{
+			query: "/^work$/",
+			match: [][]string{{"work/aws"}},
+			skip:  [][]string{{"team/work"}},
+		},
+		{
+			query: "work AND NOT work/k8s",
+			match: [][]string{{"work/aws"}},
+			skip:  [][]string{{"work/k8s/prod"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := ParseTagQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseTagQuery(%q) failed: %v", tt.query, err)
+			}
+
+			for _, tags := range tt.match {
+				if !q.Match(tags) {
+					t.Errorf("%q doesn't match %q", tt.query, tags)
+				}
+			}
+			for _, tags := range tt.skip {
+				if q.Match(tags) {
+					t.Errorf("%q matches %q", tt.query, tags)
+				}
+			}
+		})
+	}
+}
+
+func TestParseTagQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "", want: "empty query"},
+		{query: "   ", want: "empty query"},
+		{query: `work OR "on call`, want: "unterminated quote at position 9"},
+		{query: "(work OR oncall", want: "missing ')' for '(' at position 1"},
+		{query: "a AND ((b OR c)", want: "missing ')' for '(' at position 7"},
+		{query: "work AND", want: "unexpected end of query after 'AND' at position 6"},
+		{query: "work OR NOT", want: "unexpected end of query after 'NOT' at position 9"},
+		{query: "work OR OR oncall", want: "unexpected 'OR' at position 9"},
+		{query: "a )", want: "unexpected ')' at position 3"},
+		{query: "a /[/", want: "bad regular expression at position 3"},
+		{query: "a /go", want: "unterminated regular expression at position 3"},
+		{query: "lang-[", want: "bad glob 'lang-[' at position 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := ParseTagQuery(tt.query)
+			if !errors.Is(err, ErrInvalidQuery) {
+				t.Fatalf("ParseTagQuery(%q) = %v, want ErrInvalidQuery", tt.query, err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("ParseTagQuery(%q) = %v, want %q", tt.query, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnyTagQuery(t *testing.T) {
+	q := AnyTagQuery("go", "work")
+	if !q.Match([]string{"work/aws"}) || !q.Match([]string{"go"}) {
+		t.Error("AnyTagQuery doesn't match its tags or their children")
+	}
+	if q.Match([]string{"golang"}) {
+		t.Error("AnyTagQuery matches a tag that only starts with one of its tags")
+	}
+
+	other, err := ParseTagQuery("NOT work/k8s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.And(other).Match([]string{"work/k8s"}) {
+		t.Error("And doesn't require both queries to match")
+	}
+}
@@ -0,0 +1,521 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// ErrInvalidName is returned for template names that can't be used,
+	// including names that would escape the template directory
+	ErrInvalidName = errors.New("invalid Clip template name")
+
+	// ErrAmbiguous is returned when a name matches more than one template
+	ErrAmbiguous = errors.New("ambiguous Clip template name")
+
+	// ErrReadOnly is returned when a template that would be changed is
+	// only in read-only template directories
+	ErrReadOnly = errors.New("Clip template is read-only")
+)
+
+// maxSuggestions is the number of similar template names suggested when a
+// name isn't found
+const maxSuggestions = 3
+
+// NotFoundError is returned when no template matches a name. It satisfies
+// errors.Is(err, ErrNotFound), and carries the names of similar templates.
+type NotFoundError struct {
+	Name string
+
+	// Suggestions are the closest template names by edit distance
+	Suggestions []string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s: '%s'", ErrNotFound, e.Name)
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// templateExtensions are the file extensions of Clip templates, in order of
+// preference for new templates
+var templateExtensions = []string{".yml", ".yaml"}
+
+// Template is a template file found by a Resolver
+type Template struct {
+	// Name is the slash separated name of the template relative to the
+	// template directory, ie `work/oncall/handoff`
+	Name string
+
+	// Path is the location of the template file
+	Path string
+
+	// Root is the template directory the template was found in
+	Root string
+}
+
+// Resolver maps template names to files in an ordered list of template
+// directories, where templates in earlier directories shadow templates with
+// the same name in later ones. All commands go through a Resolver so names
+// are validated and resolved the same way everywhere.
+type Resolver struct {
+	// Roots are the template directories in order of precedence.
+	// Directories that don't exist are skipped.
+	Roots []string
+
+	// NewRoot is the template directory New creates templates in. If it's
+	// empty, that's the first template directory that isn't read-only.
+	NewRoot string
+
+	// ReadOnly are template directories (in Roots) whose templates can be
+	// used but not changed, like the shared system template directories
+	ReadOnly []string
+
+	// Fuzzy enables prefix and fuzzy matching of names in Find
+	Fuzzy bool
+}
+
+// NewResolver returns a Resolver for the template directories roots
+func NewResolver(roots ...string) *Resolver {
+	return &Resolver{Roots: roots}
+}
+
+// IsTemplateFile reports whether filename has a template file extension
+func IsTemplateFile(filename string) bool {
+	ext := filepath.Ext(filename)
+	for _, e := range templateExtensions {
+		if ext == e {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CleanName validates a template name and returns it in canonical form.
+// A trailing .yml/.yaml extension is dropped, and names that are empty,
+// absolute, or contain `.`/`..` elements are rejected so they can't refer
+// to files outside of the template directory.
+func CleanName(name string) (string, error) {
+	clean := name
+	for _, ext := range templateExtensions {
+		clean = strings.TrimSuffix(clean, ext)
+	}
+
+	switch {
+	case strings.TrimSpace(clean) == "":
+		return "", fmt.Errorf("%w: name can't be empty", ErrInvalidName)
+	case strings.Contains(clean, `\`):
+		return "", fmt.Errorf("%w: '%s': use '/' to separate namespaces", ErrInvalidName, name)
+	case path.IsAbs(clean) || filepath.IsAbs(clean) || filepath.VolumeName(clean) != "":
+		return "", fmt.Errorf("%w: '%s': name must be relative to the template directory", ErrInvalidName, name)
+	}
+
+	for _, part := range strings.Split(clean, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", fmt.Errorf("%w: '%s': name can't contain empty, '.' or '..' elements", ErrInvalidName, name)
+		}
+	}
+
+	return clean, nil
+}
+
+// paths returns the candidate files for a (clean) template name in root
+func paths(root, name string) ([]string, error) {
+	root = filepath.Clean(root)
+
+	var paths []string
+	for _, ext := range templateExtensions {
+		p := filepath.Join(root, filepath.FromSlash(name)+ext)
+
+		// belt and braces: CleanName should make this impossible
+		if rel, err := filepath.Rel(root, p); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%w: '%s' is outside of the template directory", ErrInvalidName, name)
+		}
+		paths = append(paths, p)
+	}
+
+	return paths, nil
+}
+
+// Resolve finds the existing template with the given name. It fails with a
+// *NotFoundError if there's no such template and ErrAmbiguous if both a .yml
+// and a .yaml file exist for the name, unless the name includes the
+// extension of the one that's wanted.
+func (r *Resolver) Resolve(name string) (Template, error) {
+	t, err := r.resolve(name)
+	if errors.Is(err, ErrNotFound) {
+		return Template{}, r.notFound(name, nil)
+	}
+
+	return t, err
+}
+
+// ResolveWritable resolves a name like Resolve, but only in the template
+// directories that aren't read-only. It's used by commands that change
+// templates, and fails with ErrReadOnly if the template is only in
+// read-only directories.
+func (r *Resolver) ResolveWritable(name string) (Template, error) {
+	t, err := r.Writable().resolve(name)
+	if !errors.Is(err, ErrNotFound) {
+		return t, err
+	}
+
+	if t, err := r.resolve(name); err == nil {
+		return Template{}, fmt.Errorf("%w: '%s' is in %s", ErrReadOnly, t.Name, t.Root)
+	}
+
+	return Template{}, r.notFound(name, nil)
+}
+
+// Writable returns a Resolver for the template directories of r that
+// aren't read-only
+func (r *Resolver) Writable() *Resolver {
+	writable := &Resolver{Fuzzy: r.Fuzzy}
+	for _, root := range r.Roots {
+		if !slices.Contains(r.ReadOnly, root) {
+			writable.Roots = append(writable.Roots, root)
+		}
+	}
+
+	return writable
+}
+
+// IsReadOnly reports whether t is in a read-only template directory
+func (r *Resolver) IsReadOnly(t Template) bool {
+	return slices.Contains(r.ReadOnly, t.Root)
+}
+
+// Find resolves a name like Resolve. If there's no template with exactly
+// that name and r.Fuzzy is set, it falls back to the template whose name
+// starts with name, and then to the template whose name contains the
+// characters of name in order (ie `stup` for `standup`). Matching is case
+// insensitive, and fails with ErrAmbiguous if more than one template
+// matches.
+func (r *Resolver) Find(name string) (Template, error) {
+	t, err := r.resolve(name)
+	if !errors.Is(err, ErrNotFound) {
+		return t, err
+	}
+	if !r.Fuzzy {
+		return Template{}, r.notFound(name, nil)
+	}
+
+	templates, err := r.List()
+	if err != nil {
+		return Template{}, err
+	}
+
+	clean, _ := CleanName(name)
+	query := strings.ToLower(clean)
+	matchers := []func(string) bool{
+		func(n string) bool { return strings.HasPrefix(n, query) },
+		func(n string) bool { return isSubsequence(query, n) },
+	}
+
+	for _, match := range matchers {
+		var matches []Template
+		for _, t := range templates {
+			if match(strings.ToLower(t.Name)) {
+				matches = append(matches, t)
+			}
+		}
+
+		switch len(matches) {
+		case 0:
+			continue
+		case 1:
+			return matches[0], nil
+		default:
+			names := make([]string, 0, len(matches))
+			for _, t := range matches {
+				names = append(names, t.Name)
+			}
+			return Template{}, fmt.Errorf("%w: '%s' matches %s", ErrAmbiguous, clean, strings.Join(slices.Compact(names), ", "))
+		}
+	}
+
+	return Template{}, r.notFound(name, templates)
+}
+
+// notFound returns a *NotFoundError for name, suggesting the templates
+// (listed from the template directory if nil) with the closest names
+func (r *Resolver) notFound(name string, templates []Template) error {
+	if clean, err := CleanName(name); err == nil {
+		name = clean
+	}
+	if templates == nil {
+		// suggestions are best effort
+		templates, _ = r.List()
+	}
+
+	type candidate struct {
+		name     string
+		distance int
+	}
+	var candidates []candidate
+	limit := max(2, utf8.RuneCountInString(name)/3)
+	for _, t := range templates {
+		// compare against the full name and the name within its namespace,
+		// so `handof` still suggests `work/oncall/handoff`
+		d := min(editDistance(name, t.Name), editDistance(name, path.Base(t.Name)))
+		if d <= limit {
+			candidates = append(candidates, candidate{name: t.Name, distance: d})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	nfErr := &NotFoundError{Name: name}
+	for _, c := range candidates {
+		if len(nfErr.Suggestions) == maxSuggestions {
+			break
+		}
+		if !slices.Contains(nfErr.Suggestions, c.name) {
+			nfErr.Suggestions = append(nfErr.Suggestions, c.name)
+		}
+	}
+
+	return nfErr
+}
+
+// resolve finds the template for name without any fuzzy matching or
+// suggestions
+func (r *Resolver) resolve(name string) (Template, error) {
+	found, err := r.lookup(name, true)
+	if err != nil {
+		return Template{}, err
+	}
+
+	return found[0], nil
+}
+
+// Lookup returns every template with the given name, in order of
+// precedence. The first one is the template Resolve returns, and the
+// rest are shadowed by it.
+func (r *Resolver) Lookup(name string) ([]Template, error) {
+	found, err := r.lookup(name, false)
+	if errors.Is(err, ErrNotFound) {
+		return nil, r.notFound(name, nil)
+	}
+
+	return found, err
+}
+
+// lookup finds the templates with the given name in each template
+// directory, stopping at the first one if first is set
+func (r *Resolver) lookup(name string, first bool) ([]Template, error) {
+	ext := filepath.Ext(name)
+	name, err := CleanName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var templates []Template
+	for _, root := range r.Roots {
+		paths, err := paths(root, name)
+		if err != nil {
+			return nil, err
+		}
+
+		var found []string
+		for _, p := range paths {
+			if IsTemplateFile(ext) && filepath.Ext(p) != ext {
+				continue
+			}
+			if info, err := os.Stat(p); err == nil && !info.IsDir() {
+				found = append(found, p)
+			}
+		}
+
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			templates = append(templates, Template{Name: name, Path: found[0], Root: root})
+		default:
+			return nil, fmt.Errorf("%w: '%s' matches both %s (include the extension to pick one)", ErrAmbiguous, name, strings.Join(found, " and "))
+		}
+
+		if first {
+			break
+		}
+	}
+
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("%w: '%s'", ErrNotFound, name)
+	}
+
+	return templates, nil
+}
+
+// New returns the template a new template with the given name should be
+// written to, which is in r.NewRoot or the first template directory that
+// isn't read-only. It fails with ErrAlreadyExists if the name is taken in
+// that directory; templates with the same name in other directories shadow
+// or are shadowed by the new one, depending on their precedence.
+func (r *Resolver) New(name string) (Template, error) {
+	root := r.NewRoot
+	if root == "" {
+		if roots := r.Writable().Roots; len(roots) > 0 {
+			root = roots[0]
+		}
+	}
+	if root == "" {
+		return Template{}, fmt.Errorf("no writable template directory to create '%s' in", name)
+	}
+
+	return NewResolver(root).newTemplate(name)
+}
+
+// NewIn is like New, but creates the template in the template directory
+// of t rather than the first one
+func (r *Resolver) NewIn(t Template, name string) (Template, error) {
+	if r.IsReadOnly(t) {
+		return Template{}, fmt.Errorf("%w: can't create '%s' in %s", ErrReadOnly, name, t.Root)
+	}
+
+	return NewResolver(t.Root).newTemplate(name)
+}
+
+func (r *Resolver) newTemplate(name string) (Template, error) {
+	name, err := CleanName(name)
+	if err != nil {
+		return Template{}, err
+	}
+
+	// a template with either extension takes the name
+	_, err = r.resolve(name)
+	switch {
+	case err == nil, errors.Is(err, ErrAmbiguous):
+		return Template{}, fmt.Errorf("%w: '%s'", ErrAlreadyExists, name)
+	case !errors.Is(err, ErrNotFound):
+		return Template{}, err
+	}
+
+	paths, err := paths(r.Roots[0], name)
+	if err != nil {
+		return Template{}, err
+	}
+
+	return Template{Name: name, Path: paths[0], Root: r.Roots[0]}, nil
+}
+
+// templateName returns the template name for a file in root
+func templateName(root, filename string) string {
+	name := strings.TrimSuffix(filename, filepath.Ext(filename))
+	if rel, err := filepath.Rel(filepath.Clean(root), name); err == nil && !strings.HasPrefix(rel, "..") {
+		name = rel
+	}
+
+	return filepath.ToSlash(name)
+}
+
+// List returns the templates in all template directories, sorted by name.
+// Only the first template with each name is included; the ones it shadows
+// can be found with Lookup. Hidden files and directories are skipped.
+func (r *Resolver) List() ([]Template, error) {
+	var templates []Template
+	seen := make(map[string]bool)
+
+	for _, root := range r.Roots {
+		// template directories are optional
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			continue
+		}
+
+		var found []Template
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			// skip hidden directories and files, like the .git and
+			// .github directories of a template repository
+			if path != root && strings.HasPrefix(info.Name(), ".") {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if !info.IsDir() && IsTemplateFile(path) {
+				found = append(found, Template{Name: templateName(root, path), Path: path, Root: root})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk template directory '%s': %w", root, err)
+		}
+
+		for _, t := range found {
+			if !seen[t.Name] {
+				templates = append(templates, t)
+			}
+		}
+		for _, t := range found {
+			seen[t.Name] = true
+		}
+	}
+
+	sort.SliceStable(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+
+	return templates, nil
+}
+
+// RemoveEmptyNamespaces removes dir and its parents if they're empty, up to
+// (but not including) the template directory they're in
+func (r *Resolver) RemoveEmptyNamespaces(dir string) {
+	dir = filepath.Clean(dir)
+
+	root := ""
+	for _, rt := range r.Roots {
+		if rt = filepath.Clean(rt); strings.HasPrefix(dir, rt+string(filepath.Separator)) {
+			root = rt
+			break
+		}
+	}
+	if root == "" {
+		return
+	}
+
+	for ; strings.HasPrefix(dir, root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		// os.Remove refuses to remove directories that aren't empty
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+	}
+}
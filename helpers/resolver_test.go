@@ -0,0 +1,168 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package helpers
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates empty files (and their directories) under root
+func writeFiles(t *testing.T, root string, names ...string) {
+	t.Helper()
+
+	for _, name := range names {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestListSkipsHiddenDirectories(t *testing.T) {
+	// a template directory that's a checked out repository
+	root := filepath.Join(t.TempDir(), ".team-templates")
+	writeFiles(t, root,
+		"standup.yml",
+		"work/oncall/handoff.yaml",
+		".github/workflows/ci.yml",
+		".git/config.yml",
+		"work/.drafts/wip.yml",
+		".hidden.yml",
+	)
+
+	templates, err := NewResolver(root).List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, tmpl := range templates {
+		names = append(names, tmpl.Name)
+	}
+	if want := []string{"standup", "work/oncall/handoff"}; !slices.Equal(names, want) {
+		t.Fatalf("List() = %v, want %v", names, want)
+	}
+}
+
+func TestCleanName(t *testing.T) {
+	valid := map[string]string{
+		"standup":                  "standup",
+		"standup.yml":              "standup",
+		"standup.yaml":             "standup",
+		"work/oncall/handoff":      "work/oncall/handoff",
+		"work/oncall/handoff.yaml": "work/oncall/handoff",
+	}
+	for name, want := range valid {
+		got, err := CleanName(name)
+		if err != nil || got != want {
+			t.Errorf("CleanName(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+
+	// names that could refer to files outside of the template directory
+	for _, name := range []string{
+		"",
+		" ",
+		"../x",
+		"a/../../x",
+		"a/..",
+		"/abs",
+		"/etc/passwd",
+		"a//b",
+		"a/",
+		`a\b`,
+		`..\x`,
+		"./x",
+		"a/./b",
+		".",
+		"..",
+	} {
+		if got, err := CleanName(name); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("CleanName(%q) = %q, %v, want ErrInvalidName", name, got, err)
+		}
+	}
+}
+
+func TestResolveRejectsTraversal(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "templates")
+	writeFiles(t, dir, "secret.yml", "templates/standup.yml")
+
+	r := NewResolver(root)
+	for _, name := range []string{"../secret", "../secret.yml", filepath.Join(dir, "secret")} {
+		if _, err := r.Resolve(name); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("Resolve(%q) = %v, want ErrInvalidName", name, err)
+		}
+		if _, err := r.New(name); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("New(%q) = %v, want ErrInvalidName", name, err)
+		}
+	}
+}
+
+func TestResolveExtensions(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, "both.yml", "both.yaml", "yaml-only.yaml")
+
+	r := NewResolver(root)
+
+	_, err := r.Resolve("both")
+	if !errors.Is(err, ErrAmbiguous) {
+		t.Fatalf("Resolve(both) = %v, want ErrAmbiguous", err)
+	}
+	if !strings.Contains(err.Error(), "include the extension") {
+		t.Errorf("ambiguous error doesn't say how to pick a file: %v", err)
+	}
+
+	// an explicit extension picks one of the files
+	for _, ext := range []string{".yml", ".yaml"} {
+		found, err := r.Resolve("both" + ext)
+		if err != nil {
+			t.Fatalf("Resolve(both%s) failed: %v", ext, err)
+		}
+		if want := filepath.Join(root, "both"+ext); found.Path != want || found.Name != "both" {
+			t.Errorf("Resolve(both%s) = %s (%s), want %s (both)", ext, found.Path, found.Name, want)
+		}
+	}
+
+	found, err := r.Resolve("yaml-only")
+	if err != nil || found.Path != filepath.Join(root, "yaml-only.yaml") {
+		t.Errorf("Resolve(yaml-only) = %v, %v", found.Path, err)
+	}
+	if _, err := r.Resolve("yaml-only.yml"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Resolve(yaml-only.yml) = %v, want ErrNotFound", err)
+	}
+
+	// either extension takes the name for new templates
+	if _, err := r.New("both"); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("New(both) = %v, want ErrAlreadyExists", err)
+	}
+	if _, err := r.New("yaml-only"); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("New(yaml-only) = %v, want ErrAlreadyExists", err)
+	}
+}
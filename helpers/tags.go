@@ -0,0 +1,91 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package helpers
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Tags can be hierarchical, with slashes between their parts. A template
+// tagged `work/aws` also counts as tagged `work` when filtering, and gets the
+// tagvars of both `work` and `work/aws`.
+
+// ParentTags returns the parents of a hierarchical tag, outermost first, so
+// `work/aws/prod` has the parents `work` and `work/aws`
+func ParentTags(tag string) []string {
+	var parents []string
+	for i, r := range tag {
+		if r == '/' && i > 0 {
+			parents = append(parents, tag[:i])
+		}
+	}
+
+	return parents
+}
+
+// IsTagOrChild reports whether tag is parent or one of its children, ie
+// `work` and `work/aws` are both `work`
+func IsTagOrChild(tag, parent string) bool {
+	return tag == parent || strings.HasPrefix(tag, parent+"/")
+}
+
+// matchTags reports whether match returns true for any of tags or their
+// parents
+func matchTags(tags []string, match func(tag string) bool) bool {
+	for _, tag := range tags {
+		if match(tag) {
+			return true
+		}
+		for _, parent := range ParentTags(tag) {
+			if match(parent) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// TagVars returns the vars that the `tagvars` section of the config file
+// sets for a template with the given tags. Parent tags are applied before
+// their children, and tags in the order they're listed, so later ones take
+// precedence.
+func TagVars(tags []string) map[string]interface{} {
+	// viper lowercases config keys, so tag names in tagvars are matched
+	// case insensitively
+	tagvars := viper.GetStringMap("tagvars")
+	if len(tagvars) == 0 {
+		return nil
+	}
+
+	var vars map[string]interface{}
+	for _, tag := range tags {
+		for _, t := range append(ParentTags(tag), tag) {
+			if v, ok := tagvars[strings.ToLower(t)].(map[string]interface{}); ok {
+				vars = MergeVars(vars, v)
+			}
+		}
+	}
+
+	return vars
+}
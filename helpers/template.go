@@ -23,7 +23,10 @@ package helpers
 import (
 	"bytes"
 	"fmt"
+	"sort"
+	gostrings "strings"
 	"text/template"
+	"text/template/parse"
 
 	"github.com/go-sprout/sprout"
 	"github.com/go-sprout/sprout/registry/checksum"
@@ -46,19 +49,83 @@ import (
 	"github.com/spf13/viper"
 )
 
-func ExecuteTemplate(tmpl TemplateFile) (string, error) {
+// MissingVarsError is returned when a template references variables that
+// aren't defined anywhere and there's no way to ask for them
+type MissingVarsError struct {
+	Names []string
+}
+
+func (e *MissingVarsError) Error() string {
+	return fmt.Sprintf("template variables not set: %s", gostrings.Join(e.Names, ", "))
+}
+
+// PromptFunc is called with the names of unresolved variables and returns
+// values for them
+type PromptFunc func(names []string, schema Schema) (map[string]interface{}, error)
+
+// RenderOptions control how ExecuteTemplate resolves variables
+type RenderOptions struct {
+	// Overlays are applied on top of the config and template vars, in
+	// order, so later overlays take precedence
+	Overlays []map[string]interface{}
+
+	// Prompt is called for variables the template needs but that aren't
+	// set. If it is nil, unresolved variables are a *MissingVarsError.
+	Prompt PromptFunc
+}
+
+func ExecuteTemplate(tmpl TemplateFile, opts RenderOptions) (string, error) {
 	var gotmpl bytes.Buffer
-	varmap := make(map[string]string)
 
-	// add default variables from config file to varmap
-	for k, v := range viper.GetStringMapString("vars") {
-		varmap[k] = v
+	// deep merge the vars from the config file, the tagvars for the tags of
+	// the template, the template, and finally anything set at runtime
+	// (values files, env, CLI flags)
+	varmap := MergeVars(nil, viper.GetStringMap("vars"))
+	varmap = MergeVars(varmap, TagVars(tmpl.Tags))
+	varmap = MergeVars(varmap, tmpl.Template.Vars)
+	for _, overlay := range opts.Overlays {
+		varmap = MergeVars(varmap, overlay)
+	}
+
+	funcs, err := templateFuncs()
+	if err != nil {
+		return "", err
 	}
-	// merge template vars to default vars from config file
-	for k, v := range tmpl.Template.Vars {
-		varmap[k] = v
+
+	t, err := template.New("Clip Template").Funcs(funcs).Parse(tmpl.Template.Text)
+	if err != nil {
+		return "", tmpl.templateError("parse", err)
 	}
 
+	// ask for anything the template needs that isn't set yet
+	missing := unresolvedVars(t, tmpl.Template.Schema, varmap)
+	if len(missing) > 0 {
+		if opts.Prompt == nil {
+			return "", &MissingVarsError{Names: missing}
+		}
+
+		answers, err := opts.Prompt(missing, tmpl.Template.Schema)
+		if err != nil {
+			return "", err
+		}
+		varmap = MergeVars(varmap, answers)
+	}
+
+	// apply defaults and check the vars against the template's schema
+	varmap, err = tmpl.Template.Schema.Validate(varmap)
+	if err != nil {
+		return "", fmt.Errorf("invalid template variables:\n%w", err)
+	}
+
+	err = t.Execute(&gotmpl, varmap)
+	if err != nil {
+		return "", tmpl.templateError("execution", err)
+	}
+
+	return gotmpl.String(), nil
+}
+
+func templateFuncs() (template.FuncMap, error) {
 	handler := sprout.New()
 	if err := handler.AddRegistries(
 		checksum.NewRegistry(),
@@ -79,14 +146,142 @@ func ExecuteTemplate(tmpl TemplateFile) (string, error) {
 		time.NewRegistry(),
 		uniqueid.NewRegistry(),
 	); err != nil {
-		return "", fmt.Errorf("failed to add sprout registries to handler: %w", err)
+		return nil, fmt.Errorf("failed to add sprout registries to handler: %w", err)
 	}
 
-	t := template.Must(template.New("Clip Template").Funcs(handler.Build()).Parse(tmpl.Template.Text))
-	err := t.Execute(&gotmpl, varmap)
-	if err != nil {
-		return "", fmt.Errorf("failed to execute template: %w", err)
+	return handler.Build(), nil
+}
+
+// unresolvedVars returns the sorted names of variables the template
+// references that aren't in vars. Variables declared in the schema only
+// count if they're required and have no default, so declaring a variable
+// without `required` marks it as optional.
+func unresolvedVars(t *template.Template, schema Schema, vars map[string]interface{}) []string {
+	// required vars are needed whether the text references them or not
+	names := ReferencedVars(t)
+	for _, name := range schema.Names() {
+		if schema[name].Required {
+			names = append(names, name)
+		}
 	}
 
-	return gotmpl.String(), nil
+	seen := make(map[string]bool)
+	var missing []string
+	for _, name := range names {
+		if _, ok := vars[name]; ok || seen[name] {
+			continue
+		}
+		if spec, ok := schema[name]; ok && (!spec.Required || spec.Default != nil) {
+			continue
+		}
+		seen[name] = true
+		missing = append(missing, name)
+	}
+
+	sort.Strings(missing)
+	return missing
+}
+
+// ReferencedVars returns the sorted names of the top level variables used
+// by a parsed template, ie `project` for both `{{ .project }}` and
+// `{{ $.project.name }}`. Fields accessed inside `range` and `with` blocks
+// are relative to the new dot, so only `$.` references are counted there.
+func ReferencedVars(t *template.Template) []string {
+	seen := make(map[string]bool)
+	if t.Tree != nil {
+		w := &varWalker{tmpl: t, seen: seen, visited: make(map[string]bool)}
+		w.walk(t.Tree.Root, true)
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// varWalker collects the variables used by a template. The bodies of
+// templates run with `{{ template "name" . }}` (including blocks) are
+// walked too, since they see the same variables.
+type varWalker struct {
+	tmpl *template.Template
+	seen map[string]bool
+
+	// visited are the defined templates already walked, which also stops
+	// recursive templates
+	visited map[string]bool
+}
+
+func (w *varWalker) walk(node parse.Node, rootDot bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			w.walk(child, rootDot)
+		}
+	case *parse.ActionNode:
+		w.walk(n.Pipe, rootDot)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			w.walk(cmd, rootDot)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			w.walk(arg, rootDot)
+		}
+	case *parse.ChainNode:
+		w.walk(n.Node, rootDot)
+	case *parse.FieldNode:
+		if rootDot && len(n.Ident) > 0 {
+			w.seen[n.Ident[0]] = true
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			w.seen[n.Ident[1]] = true
+		}
+	case *parse.IfNode:
+		w.walk(n.Pipe, rootDot)
+		w.walk(n.List, rootDot)
+		w.walk(n.ElseList, rootDot)
+	case *parse.RangeNode:
+		w.walk(n.Pipe, rootDot)
+		w.walk(n.List, false)
+		w.walk(n.ElseList, rootDot)
+	case *parse.WithNode:
+		w.walk(n.Pipe, rootDot)
+		w.walk(n.List, false)
+		w.walk(n.ElseList, rootDot)
+	case *parse.TemplateNode:
+		w.walk(n.Pipe, rootDot)
+		if isRootPipe(n.Pipe, rootDot) && !w.visited[n.Name] {
+			w.visited[n.Name] = true
+			if def := w.tmpl.Lookup(n.Name); def != nil && def.Tree != nil {
+				w.walk(def.Tree.Root, true)
+			}
+		}
+	}
+}
+
+// isRootPipe reports whether a pipeline is just the top level data, ie `.`
+// outside of range/with, or `$`
+func isRootPipe(pipe *parse.PipeNode, rootDot bool) bool {
+	if pipe == nil || len(pipe.Decl) > 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return false
+	}
+
+	switch arg := pipe.Cmds[0].Args[0].(type) {
+	case *parse.DotNode:
+		return rootDot
+	case *parse.VariableNode:
+		return len(arg.Ident) == 1 && arg.Ident[0] == "$"
+	}
+
+	return false
 }
@@ -21,14 +21,86 @@
 package helpers
 
 import (
-	"strings"
+	"os"
+	"path/filepath"
+	"slices"
 )
 
-// helper function to check if a string exists in a slice
+// helper function to check if a string exists in a slice. Only exact matches
+// count, so `go` isn't in `[golang]`.
 func Contains(slice []string, str string) bool {
-	for _, sliceString := range slice {
-		if str == sliceString || strings.Contains(sliceString, str) {
-			return true
+	return slices.Contains(slice, str)
+}
+
+// WriteFileAtomic writes data to filename like os.WriteFile, but writes a
+// temp file next to it and renames it into place, so a crash can't leave a
+// half written file behind. An existing file keeps its permissions; perm is
+// only used for new files.
+func WriteFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	if info, err := os.Stat(filename); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	// hidden, so template listings skip it while it exists
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// isSubsequence reports whether the characters of sub appear in str in
+// order, ie `stup` is a subsequence of `standup`
+func isSubsequence(sub, str string) bool {
+	rs := []rune(sub)
+	if len(rs) == 0 {
+		return true
+	}
+
+	i := 0
+	for _, r := range str {
+		if r == rs[i] {
+			i++
+			if i == len(rs) {
+				return true
+			}
 		}
 	}
 
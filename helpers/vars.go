@@ -0,0 +1,354 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Variable types that can be declared in a template's `schema`
+const (
+	VarTypeString = "string"
+	VarTypeInt    = "int"
+	VarTypeFloat  = "float"
+	VarTypeBool   = "bool"
+	VarTypeList   = "list"
+)
+
+// VarSpec declares a template variable in the `schema` section of a template
+type VarSpec struct {
+	// Type is one of string (default), int, float, bool, or list
+	Type string `yaml:"type"`
+
+	Default     interface{} `yaml:"default"`
+	Required    bool        `yaml:"required"`
+	Description string      `yaml:"description"`
+
+	// Secret values are read without echoing them when prompted for
+	Secret bool `yaml:"secret"`
+
+	// Enum restricts the value (or each list item) to a fixed set of choices
+	Enum []string `yaml:"enum"`
+
+	// Pattern is a regular expression the value (or each list item) must match
+	Pattern string `yaml:"pattern"`
+
+	// Min and Max bound the value of numbers, and the length of strings and lists
+	Min *float64 `yaml:"min"`
+	Max *float64 `yaml:"max"`
+}
+
+// Schema maps variable names to their declarations
+type Schema map[string]VarSpec
+
+// VarError describes a variable that is missing or fails validation
+type VarError struct {
+	Name   string
+	Reason string
+}
+
+func (e *VarError) Error() string {
+	return fmt.Sprintf("variable '%s' %s", e.Name, e.Reason)
+}
+
+// Names returns the declared variable names in sorted order
+func (s Schema) Names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// HasSecrets reports whether any of the variables is declared secret
+func (s Schema) HasSecrets() bool {
+	for _, spec := range s {
+		if spec.Secret {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Validate applies defaults to vars and checks them against the schema.
+// Values are converted to their declared types, so `"3"` from the config
+// file becomes the int 3 for a var declared as `type: int`. Every invalid
+// variable is reported as a *VarError in the returned error.
+func (s Schema) Validate(vars map[string]interface{}) (map[string]interface{}, error) {
+	validated := make(map[string]interface{}, len(vars))
+	for k, v := range vars {
+		validated[k] = v
+	}
+
+	var errs []error
+	for _, name := range s.Names() {
+		spec := s[name]
+
+		value, ok := validated[name]
+		if !ok || value == nil {
+			if spec.Default == nil {
+				if spec.Required {
+					errs = append(errs, &VarError{Name: name, Reason: "is required but not set"})
+				}
+				continue
+			}
+			value = spec.Default
+		}
+
+		value, err := spec.Check(value)
+		if err != nil {
+			errs = append(errs, &VarError{Name: name, Reason: err.Error()})
+			continue
+		}
+		validated[name] = value
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return validated, nil
+}
+
+// MergeVars deep merges src into dst and returns dst. Nested maps are merged
+// key by key, while any other value in src (including lists) replaces the
+// value in dst.
+func MergeVars(dst, src map[string]interface{}) map[string]interface{} {
+	if dst == nil {
+		dst = make(map[string]interface{}, len(src))
+	}
+
+	for k, v := range src {
+		v = normalizeVar(v)
+		srcMap, srcIsMap := v.(map[string]interface{})
+		dstMap, dstIsMap := dst[k].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			dst[k] = MergeVars(copyVars(dstMap), srcMap)
+		} else if srcIsMap {
+			dst[k] = MergeVars(nil, srcMap)
+		} else {
+			dst[k] = v
+		}
+	}
+
+	return dst
+}
+
+// SetVarPath sets a value in vars using a dotted path, creating nested maps
+// as needed, so `env.prod.url` sets vars["env"]["prod"]["url"]
+func SetVarPath(vars map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := vars[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			vars[key] = next
+		}
+		vars = next
+	}
+
+	vars[keys[len(keys)-1]] = value
+}
+
+func copyVars(vars map[string]interface{}) map[string]interface{} {
+	dup := make(map[string]interface{}, len(vars))
+	for k, v := range vars {
+		dup[k] = v
+	}
+
+	return dup
+}
+
+// normalizeVar converts the map types different YAML decoders produce into
+// map[string]interface{}, so nested variables can be merged and indexed
+// the same way no matter where they came from
+func normalizeVar(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[fmt.Sprint(k)] = normalizeVar(item)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[k] = normalizeVar(item)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			list[i] = normalizeVar(item)
+		}
+		return list
+	default:
+		return v
+	}
+}
+
+// Check converts value to the declared type and validates it against the
+// enum, pattern and min/max constraints of the spec
+func (spec VarSpec) Check(value interface{}) (interface{}, error) {
+	value, err := convertVar(spec.Type, value)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []interface{}{value}
+	if list, ok := value.([]interface{}); ok {
+		items = list
+	}
+
+	for _, item := range items {
+		str := fmt.Sprint(item)
+		if len(spec.Enum) > 0 && !slices.Contains(spec.Enum, str) {
+			return nil, fmt.Errorf("must be one of [%s], got '%s'", strings.Join(spec.Enum, ", "), str)
+		}
+
+		if spec.Pattern != "" {
+			re, err := regexp.Compile(spec.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("has an invalid pattern '%s': %w", spec.Pattern, err)
+			}
+			if !re.MatchString(str) {
+				return nil, fmt.Errorf("must match pattern '%s', got '%s'", spec.Pattern, str)
+			}
+		}
+	}
+
+	var size float64
+	var what string
+	switch v := value.(type) {
+	case int:
+		size, what = float64(v), "be"
+	case float64:
+		size, what = v, "be"
+	case string:
+		size, what = float64(utf8.RuneCountInString(v)), "have a length of"
+	case []interface{}:
+		size, what = float64(len(v)), "have a length of"
+	default:
+		return value, nil
+	}
+
+	if spec.Min != nil && size < *spec.Min {
+		return nil, fmt.Errorf("must %s at least %v, got %v", what, *spec.Min, size)
+	}
+	if spec.Max != nil && size > *spec.Max {
+		return nil, fmt.Errorf("must %s at most %v, got %v", what, *spec.Max, size)
+	}
+
+	return value, nil
+}
+
+func convertVar(typ string, value interface{}) (interface{}, error) {
+	switch typ {
+	case "", VarTypeString:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+		return fmt.Sprint(value), nil
+
+	case VarTypeInt:
+		switch v := value.(type) {
+		case int:
+			return v, nil
+		case int64:
+			return int(v), nil
+		case uint64:
+			return int(v), nil
+		case float64:
+			if v == math.Trunc(v) {
+				return int(v), nil
+			}
+		case string:
+			if i, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+				return i, nil
+			}
+		}
+		return nil, fmt.Errorf("must be an int, got '%v'", value)
+
+	case VarTypeFloat:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case uint64:
+			return float64(v), nil
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return f, nil
+			}
+		}
+		return nil, fmt.Errorf("must be a float, got '%v'", value)
+
+	case VarTypeBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b, nil
+			}
+		}
+		return nil, fmt.Errorf("must be a bool, got '%v'", value)
+
+	case VarTypeList:
+		switch v := value.(type) {
+		case []interface{}:
+			return v, nil
+		case []string:
+			list := make([]interface{}, len(v))
+			for i := range v {
+				list[i] = v[i]
+			}
+			return list, nil
+		case string:
+			// allow lists to be given as comma separated strings
+			var list []interface{}
+			for _, item := range strings.Split(v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+			return list, nil
+		}
+		return nil, fmt.Errorf("must be a list, got '%v'", value)
+
+	default:
+		return nil, fmt.Errorf("has an unknown type '%s' (valid types: %s, %s, %s, %s, %s)", typ, VarTypeString, VarTypeInt, VarTypeFloat, VarTypeBool, VarTypeList)
+	}
+}
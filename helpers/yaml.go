@@ -21,20 +21,34 @@
 package helpers
 
 import (
-	"fmt"
 	"os"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
 type TemplateFile struct {
-	Tags []string `yaml:"tags"`
+	Description string   `yaml:"description"`
+	Tags        []string `yaml:"tags"`
 
 	Template struct {
-		Vars map[string]string `yaml:"vars"`
+		// Vars can be any YAML value, including lists and nested maps
+		Vars map[string]interface{} `yaml:"vars"`
+
+		// Schema declares the types, defaults and constraints of variables
+		Schema Schema `yaml:"schema"`
 
 		Text string `yaml:"text"`
 	} `yaml:"template"`
+
+	// Filename is the file the template was loaded from
+	Filename string `yaml:"-"`
+
+	// source is the raw YAML, and textSpans map the template text to where
+	// it is in it. They're used to point errors in the template text at the
+	// right place in the file.
+	source    []byte
+	textSpans []textSpan
 }
 
 func LoadTemplateFile(filename string) (TemplateFile, error) {
@@ -49,18 +63,155 @@ func LoadTemplateFile(filename string) (TemplateFile, error) {
 		return TemplateFile{}, err
 	}
 
+	tmpl.Filename = filename
+	tmpl.source = bytes
+	tmpl.textSpans = locateTemplateText(bytes)
+
 	return tmpl, nil
 }
 
-func WriteConfigFile(filename string, data interface{}) error {
-	bytes, err := yaml.Marshal(data)
-	if err != nil {
-		return fmt.Errorf("could not marshal data:<%#v>...to yaml: %w", data, err)
+// textSpan records that the text of `template.text` at the 1-based line and
+// 0-based byte column textLine:textCol is found at the 1-based position
+// fileLine:fileCol in the YAML file, as are the bytes following it up to the
+// end of the line in the file
+type textSpan struct {
+	textLine, textCol int
+	fileLine, fileCol int
+}
+
+// locateTemplateText maps the text of `template.text` in a template file to
+// where it's written in the file, or returns nil if it can't be found. Block
+// scalars keep their line breaks (`|`) or fold them into spaces (`>`), and
+// plain and quoted scalars can be continued on the following lines, so each
+// line of the scalar in the file gets its own span.
+func locateTemplateText(buf []byte) []textSpan {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(buf, &doc); err != nil || len(doc.Content) == 0 {
+		return nil
 	}
 
-	err = os.WriteFile(filename, bytes, 0644)
-	if err != nil {
-		return fmt.Errorf("could not open file '%s' to write data: %w", filename, err)
+	text := mappingValue(mappingValue(doc.Content[0], "template"), "text")
+	if text == nil || text.Kind != yaml.ScalarNode {
+		return nil
+	}
+
+	lines := strings.Split(string(buf), "\n")
+	textLines := strings.Split(text.Value, "\n")
+	block := text.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0
+
+	// block scalars start on the line after the `|`/`>` indicator, indented
+	// by however much the first non-empty line is. Flow scalars start at the
+	// node, after the quote if there is one.
+	first, indent := text.Line-1, text.Column-1
+	if block {
+		first, indent = text.Line, -1
+		for n := first; n < len(lines) && indent < 0; n++ {
+			if trimmed := strings.TrimLeft(lines[n], " "); trimmed != "" {
+				indent = len(lines[n]) - len(trimmed)
+			}
+		}
+		if indent < 0 {
+			return nil
+		}
+	} else if text.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+		indent++
+	}
+
+	var spans []textSpan
+	textLine, textCol := 1, 0
+	blanks, moreIndented := 0, false
+	for n := first; n < len(lines) && textLine <= len(textLines); n++ {
+		line := strings.TrimRight(lines[n], "\r")
+
+		// where the content of the line starts
+		col := indent
+		switch {
+		case n == first && !block:
+		case block:
+			if strings.TrimSpace(line) != "" && len(line)-len(strings.TrimLeft(line, " ")) < indent {
+				return spans // the end of the block
+			}
+			col = min(indent, len(line))
+		default:
+			col = len(line) - len(strings.TrimLeft(line, " \t"))
+		}
+		if col > len(line) {
+			return spans
+		}
+		content := line[col:]
+
+		if text.Style&yaml.LiteralStyle != 0 {
+			// every line of a literal block is a line of the text
+			spans = append(spans, textSpan{textLine: n - first + 1, fileLine: n + 1, fileCol: col + 1})
+			textLine = n - first + 2
+			continue
+		}
+
+		if strings.TrimSpace(content) == "" {
+			blanks++
+			continue
+		}
+
+		// line breaks between lines of folded text become a space, unless
+		// they're followed by blank lines, which are kept instead. Leading
+		// blank lines of a folded block and the lines around more indented
+		// ones are kept as they are.
+		indented := block && strings.HasPrefix(content, " ")
+		switch {
+		case len(spans) == 0 && block:
+			textLine += blanks
+		case len(spans) == 0:
+		case block && (indented || moreIndented):
+			textLine, textCol = textLine+blanks+1, 0
+		case blanks > 0:
+			textLine, textCol = textLine+blanks, 0
+		default:
+			textCol++
+		}
+		blanks, moreIndented = 0, indented
+
+		spans = append(spans, textSpan{textLine: textLine, textCol: textCol, fileLine: n + 1, fileCol: col + 1})
+		if !block {
+			content = strings.TrimRight(content, " \t")
+		}
+		textCol += len(content)
+
+		if textLine == len(textLines) && textCol >= len(textLines[textLine-1]) {
+			break
+		}
+	}
+
+	return spans
+}
+
+// textPosition translates a position in the text of the template (a 1-based
+// line and 0-based byte column) to a 1-based line and column in its YAML
+// file, or returns 0, 0 if it isn't known
+func (tmpl TemplateFile) textPosition(line, col int) (int, int) {
+	var found *textSpan
+	for i := range tmpl.textSpans {
+		span := &tmpl.textSpans[i]
+		if span.textLine == line && span.textCol <= col {
+			found = span
+		}
+	}
+	if found == nil {
+		return 0, 0
+	}
+
+	return found.fileLine, found.fileCol + col - found.textCol
+}
+
+// mappingValue returns the value node for key in a YAML mapping node
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
 	}
 
 	return nil
@@ -0,0 +1,106 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package helpers
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestTemplateErrorPosition(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+
+		// where the error should be reported in the YAML file, and the
+		// source text starting at that position
+		line, column int
+		at           string
+	}{
+		{
+			name: "literal block with leading blank lines",
+			yaml: "template:\n  text: |\n\n\n    hello\n    x {{ index .m 3 }}\n",
+			line: 6, column: 10, at: "index .m 3",
+		},
+		{
+			name: "literal block parse error",
+			yaml: "template:\n  text: |\n\n    hello {{ .x | bogus }}\n",
+			line: 4,
+		},
+		{
+			name: "folded block",
+			yaml: "template:\n  text: >\n\n    first line\n    second {{ index .m 3 }}\n\n    third\n",
+			line: 5, column: 15, at: "index .m 3",
+		},
+		{
+			name: "double quoted scalar",
+			yaml: "template:\n  text: \"first\n    second {{ index .m 3 }}\"\n",
+			line: 3, column: 15, at: "index .m 3",
+		},
+		{
+			name: "plain scalar",
+			yaml: "template:\n  text: first\n    {{ index .m 3 }}\n",
+			line: 3, column: 8, at: "index .m 3",
+		},
+		{
+			name: "single line quoted scalar",
+			yaml: "template:\n  text: 'a {{ index .m 3 }}'\n",
+			line: 2, column: 15, at: "index .m 3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tmpl TemplateFile
+			if err := yaml.Unmarshal([]byte(tt.yaml), &tmpl); err != nil {
+				t.Fatalf("failed to parse template file: %v", err)
+			}
+			tmpl.Filename = "test.yml"
+			tmpl.source = []byte(tt.yaml)
+			tmpl.textSpans = locateTemplateText(tmpl.source)
+
+			var te *TemplateError
+			parsed, err := template.New(tmpl.Filename).Parse(tmpl.Template.Text)
+			if err != nil {
+				te = tmpl.templateError("parse", err)
+			} else {
+				err = parsed.Execute(&strings.Builder{}, map[string]interface{}{"m": []int{}})
+				if err == nil {
+					t.Fatal("expected the template to fail")
+				}
+				te = tmpl.templateError("execution", err)
+			}
+
+			if te.Line != tt.line || te.Column != tt.column {
+				t.Fatalf("error at %d:%d, want %d:%d (%v)", te.Line, te.Column, tt.line, tt.column, te)
+			}
+			if tt.at != "" {
+				line := strings.Split(tt.yaml, "\n")[te.Line-1]
+				if !strings.HasPrefix(line[te.Column-1:], tt.at) {
+					t.Fatalf("error points at %q, want %q", line[te.Column-1:], tt.at)
+				}
+			}
+		})
+	}
+}
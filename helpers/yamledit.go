@@ -0,0 +1,485 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultIndent is the indentation used for documents that don't have any
+// nested blocks to take it from
+const defaultIndent = 2
+
+// Document is a YAML file (a template or the config file) that's edited
+// through its node tree rather than by marshaling a struct or map, so the
+// comments, order of keys, blank lines between keys, indentation and style
+// of block scalars in the file are kept when it's written back.
+//
+// Keys are dotted paths of mapping keys, ie `template.text`. Like viper,
+// keys match case insensitively if there's no exact match.
+type Document struct {
+	root   *yaml.Node
+	indent int
+
+	// blankBefore holds the key nodes that had a blank line before them
+	// (or before their head comment) in the original file
+	blankBefore map[*yaml.Node]bool
+}
+
+// ParseDocument parses YAML into a Document. The top level of the document
+// must be a mapping; empty input is an empty mapping.
+func ParseDocument(buf []byte) (*Document, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(buf, &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 {
+		root = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if root.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("top level must be a mapping")
+	}
+
+	d := &Document{root: &root, indent: defaultIndent, blankBefore: make(map[*yaml.Node]bool)}
+	if indent := detectIndent(root.Content[0]); indent > 0 {
+		d.indent = indent
+	}
+
+	lines := strings.Split(string(buf), "\n")
+	walkMappingKeys(root.Content[0], func(key, _ *yaml.Node) {
+		// the line above the key and its head comment
+		above := key.Line - 1 - commentLines(key.HeadComment)
+		if above >= 1 && above <= len(lines) && strings.TrimSpace(lines[above-1]) == "" {
+			d.blankBefore[key] = true
+		}
+	})
+
+	return d, nil
+}
+
+// LoadDocument reads and parses a YAML file into a Document
+func LoadDocument(filename string) (*Document, error) {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read '%s': %w", filename, err)
+	}
+
+	d, err := ParseDocument(buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse '%s': %w", filename, err)
+	}
+
+	return d, nil
+}
+
+// Lookup returns the value node of a key, or nil if it isn't set
+func (d *Document) Lookup(key string) *yaml.Node {
+	node := d.root.Content[0]
+	for _, k := range strings.Split(key, ".") {
+		if node = mappingValueFold(node, k); node == nil {
+			return nil
+		}
+	}
+
+	return node
+}
+
+// Set sets the value of a key, creating any missing mappings. value is
+// encoded as YAML, or used as is if it's a *yaml.Node. Comments on the old
+// value are kept, and so is its style if it was a block scalar and the new
+// value is a string.
+func (d *Document) Set(key string, value interface{}) error {
+	valueNode, err := encodeNode(value)
+	if err != nil {
+		return fmt.Errorf("could not encode value for '%s': %w", key, err)
+	}
+
+	node, err := d.lookupOrCreate(key)
+	if err != nil {
+		return err
+	}
+
+	if valueNode.Kind == yaml.ScalarNode && valueNode.Tag == "!!str" && node.Kind == yaml.ScalarNode &&
+		(node.Style&(yaml.LiteralStyle|yaml.FoldedStyle)) != 0 {
+		valueNode.Style = node.Style
+	}
+
+	valueNode.HeadComment = node.HeadComment
+	valueNode.LineComment = node.LineComment
+	valueNode.FootComment = node.FootComment
+	*node = *valueNode
+
+	return nil
+}
+
+// SetBefore is like Set, but a key that isn't set yet is inserted before
+// the key before (in the same mapping) rather than at the end, if it's set
+func (d *Document) SetBefore(key, before string, value interface{}) error {
+	if d.Lookup(key) != nil {
+		return d.Set(key, value)
+	}
+
+	parentKey, name := "", key
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		parentKey, name = key[:i], key[i+1:]
+	}
+
+	parent := d.root.Content[0]
+	if parentKey != "" {
+		parent = d.Lookup(parentKey)
+	}
+
+	i := mappingIndexFold(parent, before)
+	if i < 0 {
+		return d.Set(key, value)
+	}
+
+	valueNode, err := encodeNode(value)
+	if err != nil {
+		return fmt.Errorf("could not encode value for '%s': %w", key, err)
+	}
+
+	// the new key takes over the head comment and blank line of the key it's
+	// inserted before, since those usually describe the block that follows
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}
+	if parent == d.root.Content[0] && i == 0 {
+		keyNode.HeadComment, parent.Content[i].HeadComment = parent.Content[i].HeadComment, ""
+		d.blankBefore[keyNode] = d.blankBefore[parent.Content[i]]
+	}
+	parent.Content = append(parent.Content[:i], append([]*yaml.Node{keyNode, valueNode}, parent.Content[i:]...)...)
+
+	return nil
+}
+
+// SetText sets a key to a multi-line string in literal block style (`|`),
+// like the `text` of a template
+func (d *Document) SetText(key, text string) error {
+	if err := d.Set(key, text); err != nil {
+		return err
+	}
+
+	node := d.Lookup(key)
+	node.Style = yaml.LiteralStyle
+	if !strings.Contains(text, "\n") && text != "" {
+		// a single line reads better as a plain scalar
+		node.Style = 0
+	}
+
+	return nil
+}
+
+// Delete removes a key, and reports whether it was set
+func (d *Document) Delete(key string) bool {
+	parent := d.root.Content[0]
+	keys := strings.Split(key, ".")
+	for _, k := range keys[:len(keys)-1] {
+		if parent = mappingValueFold(parent, k); parent == nil {
+			return false
+		}
+	}
+
+	i := mappingIndexFold(parent, keys[len(keys)-1])
+	if i < 0 {
+		return false
+	}
+	parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+
+	return true
+}
+
+// Append adds values to the end of the list at key, creating the list if
+// the key isn't set. A flow style list (`[a, b]`) is kept in flow style,
+// unless it was empty (`[]`).
+func (d *Document) Append(key string, values ...interface{}) error {
+	node, err := d.lookupOrCreate(key)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case node.Kind == yaml.SequenceNode:
+		if len(node.Content) == 0 && node.Style&yaml.FlowStyle != 0 {
+			node.Style &^= yaml.FlowStyle
+
+			// a comment after `[]` would end up after the next key once
+			// the list is a block, so it moves to the key instead
+			if k := d.lookupKey(key); k != nil && node.LineComment != "" && k.LineComment == "" {
+				k.LineComment, node.LineComment = node.LineComment, ""
+			}
+		}
+	case node.Kind == yaml.MappingNode && len(node.Content) == 0,
+		node.Kind == yaml.ScalarNode && node.Tag == "!!null":
+		// a new key, or one that's set to nothing
+		node.Kind, node.Tag, node.Value, node.Content = yaml.SequenceNode, "!!seq", "", nil
+	default:
+		return fmt.Errorf("can't append to '%s': it is not a list", key)
+	}
+
+	for _, value := range values {
+		item, err := encodeNode(value)
+		if err != nil {
+			return fmt.Errorf("could not encode value for '%s': %w", key, err)
+		}
+		node.Content = append(node.Content, item)
+	}
+
+	return nil
+}
+
+// RemoveItems removes the items of the list at key for which remove returns
+// true, and returns the number of items removed
+func (d *Document) RemoveItems(key string, remove func(item *yaml.Node) bool) int {
+	node := d.Lookup(key)
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return 0
+	}
+
+	kept := node.Content[:0]
+	for _, item := range node.Content {
+		if !remove(item) {
+			kept = append(kept, item)
+		}
+	}
+	removed := len(node.Content) - len(kept)
+	node.Content = kept
+
+	return removed
+}
+
+// Bytes encodes the document back to YAML
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(d.indent)
+	if err := enc.Encode(d.root); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return d.restoreLayout(buf.Bytes())
+}
+
+// WriteFile writes the document to filename, keeping the permissions of the
+// file if it exists. The file is replaced atomically, so an error can't
+// leave it half written.
+func (d *Document) WriteFile(filename string) error {
+	out, err := d.Bytes()
+	if err != nil {
+		return fmt.Errorf("could not marshal '%s': %w", filename, err)
+	}
+
+	if err := WriteFileAtomic(filename, out, 0644); err != nil {
+		return fmt.Errorf("could not write '%s': %w", filename, err)
+	}
+
+	return nil
+}
+
+// restoreLayout puts back what yaml.v3 loses when encoding: blank lines
+// between keys, and the `|` of empty literal block scalars (like the `text`
+// of a new template), which it writes as `""`. The encoded document has the
+// same shape as d.root, so it's parsed again to find the lines the keys
+// ended up on.
+func (d *Document) restoreLayout(out []byte) ([]byte, error) {
+	var encoded yaml.Node
+	if err := yaml.Unmarshal(out, &encoded); err != nil {
+		return nil, err
+	}
+
+	var keys, values, encodedKeys []*yaml.Node
+	walkMappingKeys(d.root.Content[0], func(key, value *yaml.Node) {
+		keys, values = append(keys, key), append(values, value)
+	})
+	walkMappingKeys(encoded.Content[0], func(key, _ *yaml.Node) { encodedKeys = append(encodedKeys, key) })
+	if len(keys) != len(encodedKeys) {
+		// shouldn't happen, but the layout isn't worth failing for
+		return out, nil
+	}
+
+	lines := strings.Split(string(out), "\n")
+
+	var blankAt []int
+	for i, key := range keys {
+		if d.blankBefore[key] {
+			blankAt = append(blankAt, encodedKeys[i].Line-commentLines(encodedKeys[i].HeadComment))
+		}
+
+		value := values[i]
+		if value.Kind == yaml.ScalarNode && value.Value == "" && value.Style&yaml.LiteralStyle != 0 {
+			if line := encodedKeys[i].Line; line <= len(lines) && strings.HasSuffix(lines[line-1], `: ""`) {
+				lines[line-1] = strings.TrimSuffix(lines[line-1], `""`) + "|"
+			}
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(blankAt)))
+
+	for _, at := range blankAt {
+		// lines are 1-based, and the line above may already be blank
+		if at < 2 || at > len(lines) || strings.TrimSpace(lines[at-2]) == "" {
+			continue
+		}
+		lines = append(lines[:at-1], append([]string{""}, lines[at-1:]...)...)
+	}
+
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// lookupKey returns the key node of a key, or nil if it isn't set
+func (d *Document) lookupKey(key string) *yaml.Node {
+	parent := d.root.Content[0]
+	keys := strings.Split(key, ".")
+	for _, k := range keys[:len(keys)-1] {
+		if parent = mappingValueFold(parent, k); parent == nil {
+			return nil
+		}
+	}
+
+	if i := mappingIndexFold(parent, keys[len(keys)-1]); i >= 0 {
+		return parent.Content[i]
+	}
+
+	return nil
+}
+
+// lookupOrCreate returns the value node of key, creating it (and any missing
+// mappings above it) as an empty mapping if it isn't set
+func (d *Document) lookupOrCreate(key string) (*yaml.Node, error) {
+	node := d.root.Content[0]
+	keys := strings.Split(key, ".")
+	for i, k := range keys {
+		if node.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("can't set '%s': '%s' is not a mapping", key, strings.Join(keys[:i], "."))
+		}
+		if len(node.Content) == 0 {
+			// `{}` becomes a block mapping once it has keys
+			node.Style &^= yaml.FlowStyle
+		}
+
+		next := mappingValueFold(node, k)
+		if next == nil {
+			next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, next)
+		}
+		node = next
+	}
+
+	return node, nil
+}
+
+func encodeNode(value interface{}) (*yaml.Node, error) {
+	if node, ok := value.(*yaml.Node); ok {
+		return node, nil
+	}
+
+	node := &yaml.Node{}
+	if err := node.Encode(value); err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// mappingValueFold is like mappingValue, but falls back to a case
+// insensitive match since viper treats config keys case insensitively
+func mappingValueFold(node *yaml.Node, key string) *yaml.Node {
+	if i := mappingIndexFold(node, key); i >= 0 {
+		return node.Content[i+1]
+	}
+
+	return nil
+}
+
+// mappingIndexFold returns the index of the key node for key in a mapping
+// node, or -1
+func mappingIndexFold(node *yaml.Node, key string) int {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return -1
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if strings.EqualFold(node.Content[i].Value, key) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// walkMappingKeys calls fn for every key of every block mapping under node,
+// in document order
+func walkMappingKeys(node *yaml.Node, fn func(key, value *yaml.Node)) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Style&yaml.FlowStyle == 0 {
+				fn(node.Content[i], node.Content[i+1])
+			}
+			walkMappingKeys(node.Content[i+1], fn)
+		}
+	case yaml.SequenceNode, yaml.DocumentNode:
+		for _, child := range node.Content {
+			walkMappingKeys(child, fn)
+		}
+	}
+}
+
+// detectIndent returns the indentation of the first nested block mapping
+// under node, or 0 if there isn't one
+func detectIndent(node *yaml.Node) int {
+	if node.Kind != yaml.MappingNode || node.Style&yaml.FlowStyle != 0 {
+		return 0
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if value.Kind == yaml.MappingNode && value.Style&yaml.FlowStyle == 0 && len(value.Content) > 0 {
+			if indent := value.Content[0].Column - key.Column; indent > 0 {
+				return indent
+			}
+		}
+		if indent := detectIndent(value); indent > 0 {
+			return indent
+		}
+	}
+
+	return 0
+}
+
+func commentLines(comment string) int {
+	if comment == "" {
+		return 0
+	}
+
+	return strings.Count(comment, "\n") + 1
+}
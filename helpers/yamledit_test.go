@@ -0,0 +1,280 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// documentSource is a template file with comments, blank lines between
+// keys, flow and block collections and a literal block scalar
+const documentSource = `# Clip template
+
+# what it's for
+description: old
+
+tags: [] # none yet
+
+template:
+  vars:
+    name: World # default
+  text: |
+    Hello, {{ .name }}!
+`
+
+func TestDocumentEdits(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string // documentSource if empty
+		edit   func(d *Document) error
+		want   string
+	}{
+		{
+			name: "round trip",
+			edit: func(d *Document) error { return nil },
+			want: documentSource,
+		},
+		{
+			name: "set keeps block style",
+			edit: func(d *Document) error { return d.Set("template.text", "Bye, {{ .name }}!\n") },
+			want: strings.Replace(documentSource, "Hello", "Bye", 1),
+		},
+		{
+			name: "set keeps comments",
+			edit: func(d *Document) error { return d.Set("template.vars.name", "Bob") },
+			want: strings.Replace(documentSource, "name: World # default", "name: Bob # default", 1),
+		},
+		{
+			name: "set case insensitively",
+			edit: func(d *Document) error { return d.Set("Template.Vars.Name", "Bob") },
+			want: strings.Replace(documentSource, "name: World # default", "name: Bob # default", 1),
+		},
+		{
+			name: "set creates mappings",
+			edit: func(d *Document) error { return d.Set("template.vars.env.region", "eu") },
+			want: strings.Replace(documentSource, "    name: World # default\n",
+				"    name: World # default\n    env:\n      region: eu\n", 1),
+		},
+		{
+			name:   "set multi-line text keeps literal style",
+			source: "text: |\n  one\n",
+			edit:   func(d *Document) error { return d.Set("text", "two\nthree\n") },
+			want:   "text: |\n  two\n  three\n",
+		},
+		{
+			name:   "set in empty flow mapping",
+			source: "vars: {}\n",
+			edit:   func(d *Document) error { return d.Set("vars.name", "World") },
+			want:   "vars:\n  name: World\n",
+		},
+		{
+			name:   "set in empty document",
+			source: "\n",
+			edit:   func(d *Document) error { return d.Set("editor", "vim") },
+			want:   "editor: vim\n",
+		},
+		{
+			name: "set before nested key",
+			edit: func(d *Document) error {
+				return d.SetBefore("template.schema", "text", map[string]string{"name": "string"})
+			},
+			want: strings.Replace(documentSource, "  text: |\n", "  schema:\n    name: string\n  text: |\n", 1),
+		},
+		{
+			name: "set before first key takes its comment",
+			edit: func(d *Document) error { return d.SetBefore("title", "description", "T") },
+			want: strings.Replace(documentSource, "# what it's for\ndescription: old\n",
+				"# what it's for\ntitle: T\n\ndescription: old\n", 1),
+		},
+		{
+			name: "set before missing key appends",
+			edit: func(d *Document) error { return d.SetBefore("extra", "missing", 1) },
+			want: documentSource + "extra: 1\n",
+		},
+		{
+			name: "set before existing key sets it",
+			edit: func(d *Document) error { return d.SetBefore("description", "tags", "new") },
+			want: strings.Replace(documentSource, "description: old", "description: new", 1),
+		},
+		{
+			name: "append to empty flow list",
+			edit: func(d *Document) error { return d.Append("tags", "work", "go") },
+			want: strings.Replace(documentSource, "tags: [] # none yet\n", "tags: # none yet\n  - work\n  - go\n", 1),
+		},
+		{
+			name:   "append keeps flow list",
+			source: "tags: [a, b]\n",
+			edit:   func(d *Document) error { return d.Append("tags", "c") },
+			want:   "tags: [a, b, c]\n",
+		},
+		{
+			name:   "append creates list",
+			source: "name: x\n",
+			edit:   func(d *Document) error { return d.Append("tags", "a") },
+			want:   "name: x\ntags:\n  - a\n",
+		},
+		{
+			name: "remove items",
+			edit: func(d *Document) error {
+				if err := d.Append("tags", "a", "b", "c"); err != nil {
+					return err
+				}
+				d.RemoveItems("tags", func(item *yaml.Node) bool { return item.Value != "b" })
+				return nil
+			},
+			want: strings.Replace(documentSource, "tags: [] # none yet\n", "tags: # none yet\n  - b\n", 1),
+		},
+		{
+			name: "delete",
+			edit: func(d *Document) error {
+				d.Delete("description")
+				return nil
+			},
+			want: strings.Replace(documentSource, "# what it's for\ndescription: old\n\n", "", 1),
+		},
+		{
+			name: "delete nested",
+			edit: func(d *Document) error {
+				d.Delete("template.vars")
+				return nil
+			},
+			want: strings.Replace(documentSource, "  vars:\n    name: World # default\n", "", 1),
+		},
+		{
+			name: "empty literal text",
+			edit: func(d *Document) error { return d.SetText("template.text", "") },
+			want: strings.Replace(documentSource, "    Hello, {{ .name }}!\n", "", 1),
+		},
+		{
+			name: "single line text",
+			edit: func(d *Document) error { return d.SetText("template.text", "Hi") },
+			want: strings.Replace(documentSource, "  text: |\n    Hello, {{ .name }}!\n", "  text: Hi\n", 1),
+		},
+		{
+			name:   "blank lines between nested keys",
+			source: "a:\n  b: 1\n\n  c: 2\n\nd: 3\n",
+			edit:   func(d *Document) error { return d.Set("a.c", 4) },
+			want:   "a:\n  b: 1\n\n  c: 4\n\nd: 3\n",
+		},
+		{
+			name:   "four space indent",
+			source: "a:\n    b: 1\n",
+			edit:   func(d *Document) error { return d.Set("a.c", 2) },
+			want:   "a:\n    b: 1\n    c: 2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := tt.source
+			if source == "" {
+				source = documentSource
+			}
+
+			d, err := ParseDocument([]byte(source))
+			if err != nil {
+				t.Fatalf("ParseDocument failed: %v", err)
+			}
+			if err := tt.edit(d); err != nil {
+				t.Fatalf("edit failed: %v", err)
+			}
+
+			out, err := d.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes failed: %v", err)
+			}
+			if string(out) != tt.want {
+				t.Fatalf("got:\n%s\nwant:\n%s", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentErrors(t *testing.T) {
+	if _, err := ParseDocument([]byte("- a\n- b\n")); err == nil {
+		t.Error("ParseDocument accepted a list")
+	}
+
+	d, err := ParseDocument([]byte(documentSource))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Set("description.x", 1); err == nil {
+		t.Error("Set under a scalar succeeded")
+	}
+	if err := d.Append("template", "x"); err == nil {
+		t.Error("Append to a mapping succeeded")
+	}
+	if d.Delete("missing.key") {
+		t.Error("Delete of a missing key reported it was set")
+	}
+	if n := d.RemoveItems("description", func(*yaml.Node) bool { return true }); n != 0 {
+		t.Errorf("RemoveItems on a scalar removed %d items", n)
+	}
+}
+
+func TestDocumentWriteFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "template.yml")
+	if err := os.WriteFile(filename, []byte(documentSource), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := LoadDocument(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Set("description", "new"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.WriteFile(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Replace(documentSource, "description: old", "description: new", 1); string(buf) != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", buf, want)
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("%d files in the directory, want 1", len(entries))
+	}
+}
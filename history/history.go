@@ -0,0 +1,222 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package history implements the local store of everything Clip has put on
+// the clipboard.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tjhop/clip/helpers"
+)
+
+// ErrNoEntry is returned when a history index doesn't refer to an entry
+var ErrNoEntry = errors.New("no such history entry")
+
+// Entry is a single clipboard write
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"`
+	Content string    `json:"content"`
+}
+
+// Store is a JSON file of history entries, newest first. Entries are
+// addressed by their 1-based position, so entry 1 is the most recent one.
+type Store struct {
+	Path string
+
+	// MaxEntries is the number of entries to keep (0 means unlimited)
+	MaxEntries int
+
+	// MaxAge is how long to keep entries for (0 means forever)
+	MaxAge time.Duration
+}
+
+// NewStore returns a history store persisted to path
+func NewStore(path string, maxEntries int, maxAge time.Duration) *Store {
+	return &Store{
+		Path:       path,
+		MaxEntries: maxEntries,
+		MaxAge:     maxAge,
+	}
+}
+
+// Load returns all entries in the store, newest first
+func (s *Store) Load() ([]Entry, error) {
+	buf, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	var entries []Entry
+	if len(buf) == 0 {
+		return entries, nil
+	}
+	if err := json.Unmarshal(buf, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse history file '%s': %w", s.Path, err)
+	}
+
+	return entries, nil
+}
+
+func (s *Store) save(entries []Entry) error {
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	buf, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal history: %w", err)
+	}
+
+	// history can hold anything that was copied, so keep it private
+	if err := helpers.WriteFileAtomic(s.Path, buf, 0600); err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+
+	return nil
+}
+
+// Add records a new entry. Empty content and content identical to the most
+// recent entry are skipped. Returns whether the entry was added.
+func (s *Store) Add(source, content string) (bool, error) {
+	if content == "" {
+		return false, nil
+	}
+
+	entries, err := s.Load()
+	if err != nil {
+		return false, err
+	}
+
+	if len(entries) > 0 && entries[0].Content == content {
+		return false, nil
+	}
+
+	entries = append([]Entry{{Time: time.Now(), Source: source, Content: content}}, entries...)
+	entries, _ = s.prune(entries, time.Now())
+
+	return true, s.save(entries)
+}
+
+// Get returns the entry at the 1-based index n
+func (s *Store) Get(n int) (Entry, error) {
+	entries, err := s.Load()
+	if err != nil {
+		return Entry{}, err
+	}
+
+	if n < 1 || n > len(entries) {
+		return Entry{}, fmt.Errorf("%w: %d (history has %d entries)", ErrNoEntry, n, len(entries))
+	}
+
+	return entries[n-1], nil
+}
+
+// Match is a search result and its 1-based index in the history
+type Match struct {
+	Index int
+	Entry
+}
+
+// Search returns the entries whose content contains query (case
+// insensitive), or matches it as a regular expression if useRegex is set
+func (s *Store) Search(query string, useRegex bool) ([]Match, error) {
+	var re *regexp.Regexp
+	if useRegex {
+		var err error
+		re, err = regexp.Compile(query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid search pattern: %w", err)
+		}
+	}
+
+	entries, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Match
+	for i, e := range entries {
+		if (re != nil && re.MatchString(e.Content)) ||
+			(re == nil && strings.Contains(strings.ToLower(e.Content), strings.ToLower(query))) {
+			matches = append(matches, Match{Index: i + 1, Entry: e})
+		}
+	}
+
+	return matches, nil
+}
+
+// Clear removes every entry from the history
+func (s *Store) Clear() error {
+	err := os.Remove(s.Path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove history file: %w", err)
+	}
+
+	return nil
+}
+
+// Prune applies the retention settings of the store and returns the number
+// of entries removed
+func (s *Store) Prune() (int, error) {
+	entries, err := s.Load()
+	if err != nil {
+		return 0, err
+	}
+
+	entries, removed := s.prune(entries, time.Now())
+	if removed == 0 {
+		return 0, nil
+	}
+
+	return removed, s.save(entries)
+}
+
+func (s *Store) prune(entries []Entry, now time.Time) ([]Entry, int) {
+	total := len(entries)
+
+	if s.MaxAge > 0 {
+		kept := entries[:0]
+		for _, e := range entries {
+			if now.Sub(e.Time) <= s.MaxAge {
+				kept = append(kept, e)
+			}
+		}
+		entries = kept
+	}
+
+	if s.MaxEntries > 0 && len(entries) > s.MaxEntries {
+		entries = entries[:s.MaxEntries]
+	}
+
+	return entries, total - len(entries)
+}
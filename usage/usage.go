@@ -0,0 +1,178 @@
+// Copyright © 2019 TJ Hoplock <t.hoplock@gmail.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package usage records when Clip templates are copied, so they can be
+// sorted by how recently and how often they're used.
+package usage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tjhop/clip/helpers"
+)
+
+// maxRecent is the number of recent uses kept per template for frecency
+const maxRecent = 10
+
+// Stats is the usage of a single template
+type Stats struct {
+	Count    int       `json:"count"`
+	LastUsed time.Time `json:"last_used"`
+
+	// Recent are the times of the most recent uses, newest first
+	Recent []time.Time `json:"recent"`
+}
+
+// Frecency scores how frequently and recently a template is used. Each
+// recent use is weighted by its age, and the average weight is scaled by
+// the total number of uses, so a template used often long ago ranks below
+// one used a few times this week.
+func (s Stats) Frecency(now time.Time) float64 {
+	if len(s.Recent) == 0 {
+		return 0
+	}
+
+	var score float64
+	for _, t := range s.Recent {
+		score += ageWeight(now.Sub(t))
+	}
+
+	return score * float64(s.Count) / float64(len(s.Recent))
+}
+
+func ageWeight(age time.Duration) float64 {
+	const day = 24 * time.Hour
+
+	switch {
+	case age <= 4*day:
+		return 100
+	case age <= 14*day:
+		return 70
+	case age <= 31*day:
+		return 50
+	case age <= 90*day:
+		return 30
+	default:
+		return 10
+	}
+}
+
+// Store is a JSON file of usage stats, keyed by template name
+type Store struct {
+	Path string
+}
+
+// NewStore returns a usage store persisted to path
+func NewStore(path string) *Store {
+	return &Store{Path: path}
+}
+
+// Load returns the usage stats of every template that has been used
+func (s *Store) Load() (map[string]Stats, error) {
+	stats := make(map[string]Stats)
+
+	buf, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && len(buf) == 0) {
+		return stats, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage file: %w", err)
+	}
+
+	if err := json.Unmarshal(buf, &stats); err != nil {
+		return nil, fmt.Errorf("failed to parse usage file '%s': %w", s.Path, err)
+	}
+
+	return stats, nil
+}
+
+func (s *Store) save(stats map[string]Stats) error {
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create usage directory: %w", err)
+	}
+
+	buf, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage: %w", err)
+	}
+
+	if err := helpers.WriteFileAtomic(s.Path, buf, 0600); err != nil {
+		return fmt.Errorf("failed to write usage file: %w", err)
+	}
+
+	return nil
+}
+
+// Record records a use of the named template
+func (s *Store) Record(name string) error {
+	stats, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	st := stats[name]
+	st.Count++
+	st.LastUsed = now
+	st.Recent = append([]time.Time{now}, st.Recent...)
+	if len(st.Recent) > maxRecent {
+		st.Recent = st.Recent[:maxRecent]
+	}
+	stats[name] = st
+
+	return s.save(stats)
+}
+
+// Rename moves the usage stats of a template to its new name
+func (s *Store) Rename(oldName, newName string) error {
+	stats, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	st, ok := stats[oldName]
+	if !ok {
+		return nil
+	}
+	delete(stats, oldName)
+	stats[newName] = st
+
+	return s.save(stats)
+}
+
+// Remove forgets the usage stats of a template
+func (s *Store) Remove(name string) error {
+	stats, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := stats[name]; !ok {
+		return nil
+	}
+	delete(stats, name)
+
+	return s.save(stats)
+}